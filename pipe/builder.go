@@ -3,13 +3,42 @@ package pipe
 import (
 	"fmt"
 	"reflect"
+	"sort"
+)
+
+// pipeNode provides type-agnostic access to the properties that are common to all the nodes.
+// Its methods must accept nil receivers, as start and final nodes can be nil.
+type pipeNode interface {
+	setName(name string)
+	nodeName() string
+	kind() nodeKind
+	isNil() bool
+	// destinations returns the nodes that have been passed to the SendTo method
+	destinations() []pipeNode
+	// feedbackDestinations returns the nodes that have been passed to the FeedbackTo method
+	feedbackDestinations() []pipeNode
+	// describe returns the public information of the node. It is only invoked for non-nil nodes
+	describe() NodeInfo
+	// nodeStats returns a snapshot of the runtime metrics of the node. It is only invoked for non-nil nodes
+	nodeStats() NodeStats
+}
+
+type nodeKind int
+
+const (
+	startKind nodeKind = iota
+	middleKind
+	bypassKind
+	finalKind
 )
 
 type startable interface {
-	Start()
+	pipeNode
+	start(rs *runState)
 }
 
 type doneable interface {
+	pipeNode
 	Done() <-chan struct{}
 }
 
@@ -22,9 +51,8 @@ type Builder[IMPL NodesMap] struct {
 	// in the NodesMap implementation.
 	// this way we make sure that we can assign a node to a field twice and only
 	// tha last change will prevail, without leaving lost startnodes around there
-	startNodes map[uintptr]nodeOrProvider[startable]
-	// in middle nodes, we only care about providers and we don't really care about the node implementation
-	middleNodes map[uintptr]nodeOrProvider[struct{}]
+	startNodes  map[uintptr]nodeOrProvider[startable]
+	middleNodes map[uintptr]nodeOrProvider[pipeNode]
 	finalNodes  map[uintptr]nodeOrProvider[doneable]
 }
 
@@ -42,7 +70,7 @@ func NewBuilder[IMPL NodesMap](nodesMap IMPL, defaultOpts ...Option) *Builder[IM
 		nodesMap:    nodesMap,
 		opts:        defaultOpts,
 		startNodes:  map[uintptr]nodeOrProvider[startable]{},
-		middleNodes: map[uintptr]nodeOrProvider[struct{}]{},
+		middleNodes: map[uintptr]nodeOrProvider[pipeNode]{},
 		finalNodes:  map[uintptr]nodeOrProvider[doneable]{},
 	}
 }
@@ -64,6 +92,7 @@ type reflectProvider struct {
 	asNode         reflect.Value
 	fieldGetter    reflect.Value
 	fn             reflect.Value
+	opts           []Option
 }
 
 func (rp *reflectProvider) call(nodesMap interface{}) (reflect.Value, uintptr, error) {
@@ -86,8 +115,12 @@ func (rp *reflectProvider) call(nodesMap interface{}) (reflect.Value, uintptr, e
 			return reflect.Value{}, 0, fmt.Errorf("middle provider returned a nil function. Expecting %s", nodeFn.Type().String())
 		}
 	} else {
-		// node = AsNode(nodeFn)
-		node = rp.asNode.Call([]reflect.Value{nodeFn})[0]
+		// node = AsNode(nodeFn, opts...)
+		args := []reflect.Value{nodeFn}
+		for _, opt := range rp.opts {
+			args = append(args, reflect.ValueOf(opt))
+		}
+		node = rp.asNode.Call(args)[0]
 	}
 	// *fieldPtr = AsNode(nodeFn)
 	fieldPtr.Elem().Set(node)
@@ -100,6 +133,11 @@ func (b *Builder[IMPL]) Build() (*Runner, error) {
 		startNodes: map[uintptr]startable{},
 		finalNodes: map[uintptr]doneable{},
 	}
+	options := getOptions(b.opts...)
+	runner.state = newRunState(&options)
+	names := fieldNames(b.nodesMap)
+	// nodes by the address of their NodesMap field, to sort them as in the NodesMap
+	nodes := map[uintptr]pipeNode{}
 	for dstPtr, sn := range b.startNodes {
 		if sp := sn.provider; sp == nil {
 			// node explicitly set via AddStart, AddMiddle, AddFinal
@@ -108,20 +146,27 @@ func (b *Builder[IMPL]) Build() (*Runner, error) {
 			// node provided from AddStartProvider argument func
 			sp := sn.provider
 			if node, dstFieldPtr, err := sp.call(b.nodesMap); err != nil {
-				return nil, fmt.Errorf("invoking Start node provider: %w", err)
+				return nil, fmt.Errorf("invoking Start node provider %s: %w", names.of(dstPtr), err)
 			} else {
 				runner.startNodes[dstFieldPtr] = node.Interface().(startable)
 			}
 		}
 	}
-	for _, mn := range b.middleNodes {
-		// we only care about nodes added by AddMiddleProviders, as nodes
-		// from AddMiddle are already created and assigned to its field
+	for dstPtr, sn := range runner.startNodes {
+		sn.setName(names.of(dstPtr))
+		nodes[dstPtr] = sn
+	}
+	for dstPtr, mn := range b.middleNodes {
+		mnode := mn.node
 		if mp := mn.provider; mp != nil {
-			if _, _, err := mp.call(b.nodesMap); err != nil {
-				return nil, fmt.Errorf("invoking Middle node provider: %w", err)
+			node, _, err := mp.call(b.nodesMap)
+			if err != nil {
+				return nil, fmt.Errorf("invoking Middle node provider %s: %w", names.of(dstPtr), err)
 			}
+			mnode = node.Interface().(pipeNode)
 		}
+		mnode.setName(names.of(dstPtr))
+		nodes[dstPtr] = mnode
 	}
 	for dstPtr, fn := range b.finalNodes {
 		if fp := fn.provider; fp == nil {
@@ -130,12 +175,67 @@ func (b *Builder[IMPL]) Build() (*Runner, error) {
 		} else {
 			// node provided from AddMiddleProvider argument func
 			if node, dstFieldPtr, err := fp.call(b.nodesMap); err != nil {
-				return nil, fmt.Errorf("invoking Final node provider: %w", err)
+				return nil, fmt.Errorf("invoking Final node provider %s: %w", names.of(dstPtr), err)
 			} else {
 				runner.finalNodes[dstFieldPtr] = node.Interface().(doneable)
 			}
 		}
 	}
+	for dstPtr, fn := range runner.finalNodes {
+		fn.setName(names.of(dstPtr))
+		nodes[dstPtr] = fn
+	}
+	runner.nodes = sortByAddress(nodes)
 	b.nodesMap.Connect()
+	if err := validate(runner.nodes); err != nil {
+		return nil, err
+	}
+	setLoops(runner.nodes)
 	return runner, nil
 }
+
+func sortByAddress(nodes map[uintptr]pipeNode) []pipeNode {
+	addrs := make([]uintptr, 0, len(nodes))
+	for addr := range nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	sorted := make([]pipeNode, 0, len(addrs))
+	for _, addr := range addrs {
+		sorted = append(sorted, nodes[addr])
+	}
+	return sorted
+}
+
+// nodeNames maps the address of each NodesMap field to its name
+type nodeNames map[uintptr]string
+
+// fieldNames returns the names of the fields of the NodesMap, if it is a pointer to a struct,
+// or the names that are provided by a NamedNodesMap
+func fieldNames(nodesMap interface{}) nodeNames {
+	names := nodeNames{}
+	if named, ok := nodesMap.(NamedNodesMap); ok {
+		for ptr, name := range named.NodeNames() {
+			names[reflect.ValueOf(ptr).Pointer()] = name
+		}
+		return names
+	}
+	nm := reflect.ValueOf(nodesMap)
+	if nm.Kind() != reflect.Pointer || nm.Elem().Kind() != reflect.Struct {
+		return names
+	}
+	nm = nm.Elem()
+	for f := 0; f < nm.NumField(); f++ {
+		names[nm.Field(f).UnsafeAddr()] = nm.Type().Field(f).Name
+	}
+	return names
+}
+
+// of returns the name of the NodesMap field in the given address. If the address
+// does not belong to any field of the NodesMap, the address is returned as name.
+func (n nodeNames) of(fieldPtr uintptr) string {
+	if name, ok := n[fieldPtr]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#x", fieldPtr)
+}
@@ -59,3 +59,104 @@ func TestError_FinalNode(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorIs(t, err, FinalError{})
 }
+
+type invalidPipe struct {
+	start       pipe.Start[int]
+	danglingMid pipe.Middle[int, int]
+	loopMid     pipe.Middle[int, int]
+	bypass      pipe.Middle[int, int]
+	final       pipe.Final[int]
+	orphan      pipe.Final[int]
+	undefined   pipe.Final[int]
+}
+
+func (n *invalidPipe) Connect() {
+	n.start.SendTo(n.danglingMid, n.loopMid, n.bypass)
+	n.loopMid.SendTo(n.loopMid, n.final)
+	n.bypass.SendTo(n.undefined)
+}
+
+func TestValidation(t *testing.T) {
+	b := pipe.NewBuilder(&invalidPipe{})
+	pipe.AddStart(b, func(n *invalidPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	pipe.AddMiddle(b, func(n *invalidPipe) *pipe.Middle[int, int] { return &n.danglingMid }, EvenFilter)
+	pipe.AddMiddle(b, func(n *invalidPipe) *pipe.Middle[int, int] { return &n.loopMid }, OddFilter)
+	pipe.AddMiddleProvider(b, func(n *invalidPipe) *pipe.Middle[int, int] { return &n.bypass },
+		func() (pipe.MiddleFunc[int, int], error) {
+			return pipe.Bypass[int](), nil
+		})
+	pipe.AddFinal(b, func(n *invalidPipe) *pipe.Final[int] { return &n.final }, func(in <-chan int) {})
+	pipe.AddFinal(b, func(n *invalidPipe) *pipe.Final[int] { return &n.orphan }, func(in <-chan int) {})
+
+	_, err := b.Build()
+	require.Error(t, err)
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, &pipe.ValidationError{
+		Dangling:              []string{"bypass", "danglingMid"},
+		Unreachable:           []string{"orphan"},
+		SelfLoops:             []string{"loopMid"},
+		UndefinedDestinations: []string{"bypass"},
+	}, verr)
+	assert.Equal(t, "invalid pipeline: dangling nodes [bypass, danglingMid]; unreachable nodes [orphan]; "+
+		"nodes sending data to themselves [loopMid]; nodes sending data to undefined nodes [bypass]", err.Error())
+}
+
+type ignoredNodesPipe struct {
+	start        pipe.Start[int]
+	ignoredStart pipe.Start[int]
+	mid          pipe.Middle[int, int]
+	ignoredFinal pipe.Final[int]
+	final        pipe.Final[int]
+}
+
+func (n *ignoredNodesPipe) Connect() {
+	n.start.SendTo(n.mid)
+	n.ignoredStart.SendTo(n.mid)
+	n.mid.SendTo(n.ignoredFinal)
+}
+
+func TestValidation_IgnoredNodes(t *testing.T) {
+	b := pipe.NewBuilder(&ignoredNodesPipe{})
+	pipe.AddStart(b, func(n *ignoredNodesPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	pipe.AddStart(b, func(n *ignoredNodesPipe) *pipe.Start[int] { return &n.ignoredStart }, pipe.IgnoreStart[int]())
+	pipe.AddMiddle(b, func(n *ignoredNodesPipe) *pipe.Middle[int, int] { return &n.mid }, EvenFilter)
+	pipe.AddFinal(b, func(n *ignoredNodesPipe) *pipe.Final[int] { return &n.ignoredFinal }, pipe.IgnoreFinal[int]())
+	pipe.AddFinal(b, func(n *ignoredNodesPipe) *pipe.Final[int] { return &n.final }, pipe.IgnoreFinal[int]())
+
+	// a middle node whose only destination is ignored is a dangling node
+	_, err := b.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, &pipe.ValidationError{Dangling: []string{"mid"}}, verr)
+}
+
+type cyclicPipe struct {
+	start pipe.Start[int]
+	a     pipe.Middle[int, int]
+	b     pipe.Middle[int, int]
+	c     pipe.Middle[int, int]
+	final pipe.Final[int]
+}
+
+func (n *cyclicPipe) Connect() {
+	n.start.SendTo(n.b)
+	n.b.SendTo(n.c)
+	n.c.SendTo(n.a, n.final)
+	n.a.SendTo(n.b)
+}
+
+func TestValidation_Cycles(t *testing.T) {
+	b := pipe.NewBuilder(&cyclicPipe{})
+	pipe.AddStart(b, func(n *cyclicPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	pipe.AddMiddle(b, func(n *cyclicPipe) *pipe.Middle[int, int] { return &n.a }, EvenFilter)
+	pipe.AddMiddle(b, func(n *cyclicPipe) *pipe.Middle[int, int] { return &n.b }, EvenFilter)
+	pipe.AddMiddle(b, func(n *cyclicPipe) *pipe.Middle[int, int] { return &n.c }, EvenFilter)
+	pipe.AddFinal(b, func(n *cyclicPipe) *pipe.Final[int] { return &n.final }, func(in <-chan int) {})
+
+	_, err := b.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, &pipe.ValidationError{Cycles: [][]string{{"a", "b", "c", "a"}}}, verr)
+	assert.Equal(t, "invalid pipeline: cycle [a -> b -> c -> a]", err.Error())
+}
@@ -1,6 +1,10 @@
 package pipe
 
-import "github.com/mariomac/pipes/pipe/internal/connect"
+import (
+	"errors"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
 
 // IgnoreStart is a convenience function to explicitly specify that the returned StartFunc
 // is going to be ignored/bypassed by the pipes library.
@@ -32,11 +36,55 @@ func IgnoreFinal[IN any]() FinalFunc[IN] {
 // forward data to the destination nodes.
 // TODO: merge with middle node?
 type bypass[INOUT any] struct {
-	outs []Receiver[INOUT]
+	name         string
+	outs         []Receiver[INOUT]
+	feedbackOuts []Receiver[INOUT]
+}
+
+func (b *bypass[INOUT]) setName(name string) {
+	b.name = name
+}
+
+func (b *bypass[INOUT]) nodeName() string {
+	return b.name
+}
+
+func (b *bypass[INOUT]) kind() nodeKind {
+	return bypassKind
+}
+
+func (b *bypass[INOUT]) isNil() bool {
+	return false
+}
+
+func (b *bypass[INOUT]) destinations() []pipeNode {
+	return asPipeNodes(b.outs)
+}
+
+func (b *bypass[INOUT]) feedbackDestinations() []pipeNode {
+	return asPipeNodes(b.feedbackOuts)
+}
+
+func (b *bypass[INOUT]) describe() NodeInfo {
+	return NodeInfo{
+		Name:     b.name,
+		Kind:     MiddleKind,
+		In:       typeOf[INOUT](),
+		Out:      typeOf[INOUT](),
+		Bypassed: true,
+	}
+}
+
+func (b *bypass[INOUT]) nodeStats() NodeStats {
+	return NodeStats{}
 }
 
 func (b *bypass[INOUT]) SendTo(r ...Receiver[INOUT]) {
-	b.outs = append(b.outs, r...)
+	b.outs = appendReceivers(b.outs, r...)
+}
+
+func (b *bypass[INOUT]) FeedbackTo(r ...Receiver[INOUT]) {
+	b.feedbackOuts = appendReceivers(b.feedbackOuts, r...)
 }
 
 // nolint:unused
@@ -47,17 +95,26 @@ func (b *bypass[INOUT]) isStarted() bool {
 	for _, o := range b.outs {
 		started = started && o.isStarted()
 	}
+	for _, o := range b.feedbackOuts {
+		started = started && o.isStarted()
+	}
 	return started
 }
 
 //nolint:unused
-func (b *bypass[INOUT]) start() {
-	if len(b.outs) == 0 {
-		panic("bypass node should have outputs")
+func (b *bypass[INOUT]) start(rs *runState) {
+	if len(b.outs) == 0 && len(b.feedbackOuts) == 0 {
+		rs.nodeError(b.name, errors.New("bypass node should have outputs"))
+		return
 	}
 	for _, o := range b.outs {
 		if !o.isStarted() {
-			o.start()
+			o.start(rs)
+		}
+	}
+	for _, o := range b.feedbackOuts {
+		if !o.isStarted() {
+			o.start(rs)
 		}
 	}
 }
@@ -65,9 +122,26 @@ func (b *bypass[INOUT]) start() {
 // nolint:unused
 // golangci-lint bug: it's actually used through its interface
 func (b *bypass[INOUT]) joiners() []*connect.Joiner[INOUT] {
-	joiners := make([]*connect.Joiner[INOUT], 0, len(b.outs))
+	joiners := make([]*connect.Joiner[INOUT], 0, len(b.outs)+len(b.feedbackOuts))
 	for _, o := range b.outs {
 		joiners = append(joiners, o.joiners()...)
 	}
+	for _, o := range b.feedbackOuts {
+		joiners = append(joiners, o.feedbackJoiners()...)
+	}
+	return joiners
+}
+
+// nolint:unused
+// golangci-lint bug: it's actually used through its interface
+func (b *bypass[INOUT]) feedbackJoiners() []*connect.Joiner[INOUT] {
+	// any data that is fed back to a bypassed node is fed back to all its destinations
+	joiners := make([]*connect.Joiner[INOUT], 0, len(b.outs)+len(b.feedbackOuts))
+	for _, o := range b.outs {
+		joiners = append(joiners, o.feedbackJoiners()...)
+	}
+	for _, o := range b.feedbackOuts {
+		joiners = append(joiners, o.feedbackJoiners()...)
+	}
 	return joiners
 }
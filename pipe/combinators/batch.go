@@ -0,0 +1,77 @@
+package combinators
+
+import (
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// Batch returns a MiddleFunc that groups the input items into batches, in the same order
+// as they are received. A batch is forwarded when any of the following conditions happen:
+//   - it reaches maxSize items.
+//   - maxAge has passed since its first item was received.
+//   - the input channel is closed.
+//
+// A maxSize or maxAge lower or equal than zero disables the respective condition.
+// Each forwarded batch is a new slice, so the receiver can keep it.
+func Batch[IN any](maxSize int, maxAge time.Duration, opts ...Option) pipe.MiddleFunc[IN, []IN] {
+	clock := getOptions(opts...).clock
+	return func(in <-chan IN, out chan<- []IN) {
+		var batch []IN
+		var timer Timer
+		// expired is nil while there isn't any pending batch, or its age is not limited
+		var expired <-chan time.Time
+		flush := func() {
+			if timer != nil {
+				timer.Stop()
+				timer, expired = nil, nil
+			}
+			out <- batch
+			batch = nil
+		}
+		for {
+			select {
+			case item, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						flush()
+					}
+					return
+				}
+				if batch == nil {
+					batch = newBatch[IN](maxSize)
+					if maxAge > 0 {
+						timer = clock.NewTimer(maxAge)
+						expired = timer.C()
+					}
+				}
+				batch = append(batch, item)
+				if maxSize > 0 && len(batch) >= maxSize {
+					flush()
+				}
+			case <-expired:
+				timer, expired = nil, nil
+				flush()
+			}
+		}
+	}
+}
+
+func newBatch[IN any](maxSize int) []IN {
+	if maxSize > 0 {
+		return make([]IN, 0, maxSize)
+	}
+	return []IN{}
+}
+
+// Unbatch returns a MiddleFunc that forwards, one by one, the items of each input batch.
+// It is the inverse of Batch.
+func Unbatch[IN any]() pipe.MiddleFunc[[]IN, IN] {
+	return func(in <-chan []IN, out chan<- IN) {
+		for batch := range in {
+			for _, item := range batch {
+				out <- item
+			}
+		}
+	}
+}
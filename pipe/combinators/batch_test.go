@@ -0,0 +1,101 @@
+package combinators_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+const timeout = 2 * time.Second
+
+func TestBatch_MaxSize(t *testing.T) {
+	in, out := make(chan int), make(chan []int, 10)
+	go func() {
+		combinators.Batch[int](3, time.Hour)(in, out)
+		close(out)
+	}()
+	for i := 1; i <= 7; i++ {
+		in <- i
+	}
+	close(in)
+	assert.Equal(t, []int{1, 2, 3}, helpers.ReadChannel(t, out, timeout))
+	assert.Equal(t, []int{4, 5, 6}, helpers.ReadChannel(t, out, timeout))
+	// the pending items are flushed when the input is closed
+	assert.Equal(t, []int{7}, helpers.ReadChannel(t, out, timeout))
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestBatch_MaxAge(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	in, out := make(chan int), make(chan []int, 10)
+	go func() {
+		combinators.Batch[int](10, time.Second, combinators.WithClock(clock))(in, out)
+		close(out)
+	}()
+	// the timer starts with the first item of the batch
+	in <- 1
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+	in <- 2
+	clock.Advance(500 * time.Millisecond)
+	in <- 3
+	select {
+	case b := <-out:
+		require.Failf(t, "unexpected batch", "%v", b)
+	default:
+	}
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, []int{1, 2, 3}, helpers.ReadChannel(t, out, timeout))
+
+	in <- 4
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+	clock.Advance(time.Second)
+	assert.Equal(t, []int{4}, helpers.ReadChannel(t, out, timeout))
+
+	// no empty batches are forwarded
+	clock.Advance(time.Hour)
+	close(in)
+	_, ok := <-out
+	assert.False(t, ok)
+	assert.Zero(t, clock.Timers())
+}
+
+type batchPipe struct {
+	numbers pipe.Start[int]
+	batch   pipe.Middle[int, []int]
+	unbatch pipe.Middle[[]int, int]
+	final   pipe.Final[int]
+}
+
+func (b *batchPipe) Connect() {
+	b.numbers.SendTo(b.batch)
+	b.batch.SendTo(b.unbatch)
+	b.unbatch.SendTo(b.final)
+}
+
+func TestUnbatch(t *testing.T) {
+	b := pipe.NewBuilder(&batchPipe{})
+	pipe.AddStart(b, func(b *batchPipe) *pipe.Start[int] { return &b.numbers }, func(out chan<- int) {
+		for i := 1; i <= 10; i++ {
+			out <- i
+		}
+	})
+	pipe.AddMiddle(b, func(b *batchPipe) *pipe.Middle[int, []int] { return &b.batch },
+		combinators.Batch[int](4, time.Millisecond))
+	pipe.AddMiddle(b, func(b *batchPipe) *pipe.Middle[[]int, int] { return &b.unbatch },
+		combinators.Unbatch[int]())
+	var collected []int
+	pipe.AddFinal(b, func(b *batchPipe) *pipe.Final[int] { return &b.final },
+		combinators.ForEach(func(i int) { collected = append(collected, i) }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	require.NoError(t, r.Wait())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, collected)
+}
@@ -0,0 +1,70 @@
+package combinators
+
+import "time"
+
+// Clock provides the current time and the timers to the time-based combinators (e.g. Batch).
+// It can be replaced by means of the WithClock option, e.g. to control the time in the tests.
+type Clock interface {
+	Now() time.Time
+	// NewTimer creates a Timer that sends the current time on its channel after at
+	// least the duration d
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the Clock equivalent of the standard time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the timer fires
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing. It returns false if the timer has already
+	// fired or been stopped.
+	Stop() bool
+}
+
+// SystemClock returns the Clock that is used by default, based on the standard time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{t: time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (s systemTimer) C() <-chan time.Time {
+	return s.t.C
+}
+
+func (s systemTimer) Stop() bool {
+	return s.t.Stop()
+}
+
+// Option configures the time-based combinators.
+type Option func(*options)
+
+type options struct {
+	clock Clock
+}
+
+func getOptions(opts ...Option) options {
+	o := options{clock: SystemClock()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock replaces the Clock of a combinator.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
@@ -0,0 +1,154 @@
+// Package combinators provides functions that turn item-level functions into node functions
+// that can be directly passed to the pipe.AddMiddle and pipe.AddFinal functions (and their
+// Err variants), or returned by node providers. For example:
+//
+//	pipe.AddMiddle(b, matcherPtr, combinators.Filter(func(l FileLine) bool {
+//		return matcher.MatchString(l.Line)
+//	}))
+//
+// The Err variants of each combinator return, and then report to the pipe.Runner, the first
+// error that is returned by the item-level function. After that, the node stops processing
+// its input and closes its output.
+package combinators
+
+import "github.com/mariomac/pipes/pipe"
+
+// Map returns a MiddleFunc that forwards the result of applying the mapper
+// function to each input item.
+func Map[IN, OUT any](mapper func(IN) OUT) pipe.MiddleFunc[IN, OUT] {
+	return func(in <-chan IN, out chan<- OUT) {
+		for i := range in {
+			out <- mapper(i)
+		}
+	}
+}
+
+// MapErr returns a MiddleFuncErr that forwards the result of applying the mapper
+// function to each input item, until the mapper returns an error.
+func MapErr[IN, OUT any](mapper func(IN) (OUT, error)) pipe.MiddleFuncErr[IN, OUT] {
+	return func(in <-chan IN, out chan<- OUT) error {
+		for i := range in {
+			o, err := mapper(i)
+			if err != nil {
+				return err
+			}
+			out <- o
+		}
+		return nil
+	}
+}
+
+// Filter returns a MiddleFunc that only forwards the input items that satisfy
+// the predicate function.
+func Filter[T any](predicate func(T) bool) pipe.MiddleFunc[T, T] {
+	return func(in <-chan T, out chan<- T) {
+		for i := range in {
+			if predicate(i) {
+				out <- i
+			}
+		}
+	}
+}
+
+// FilterErr returns a MiddleFuncErr that only forwards the input items that satisfy
+// the predicate function, until the predicate returns an error.
+func FilterErr[T any](predicate func(T) (bool, error)) pipe.MiddleFuncErr[T, T] {
+	return func(in <-chan T, out chan<- T) error {
+		for i := range in {
+			ok, err := predicate(i)
+			if err != nil {
+				return err
+			}
+			if ok {
+				out <- i
+			}
+		}
+		return nil
+	}
+}
+
+// FlatMap returns a MiddleFunc that forwards, one by one, all the items that are
+// returned by applying the mapper function to each input item.
+func FlatMap[IN, OUT any](mapper func(IN) []OUT) pipe.MiddleFunc[IN, OUT] {
+	return func(in <-chan IN, out chan<- OUT) {
+		for i := range in {
+			for _, o := range mapper(i) {
+				out <- o
+			}
+		}
+	}
+}
+
+// FlatMapErr returns a MiddleFuncErr that forwards, one by one, all the items that are
+// returned by applying the mapper function to each input item, until the mapper returns
+// an error. The items that are returned along with an error are not forwarded.
+func FlatMapErr[IN, OUT any](mapper func(IN) ([]OUT, error)) pipe.MiddleFuncErr[IN, OUT] {
+	return func(in <-chan IN, out chan<- OUT) error {
+		for i := range in {
+			outs, err := mapper(i)
+			if err != nil {
+				return err
+			}
+			for _, o := range outs {
+				out <- o
+			}
+		}
+		return nil
+	}
+}
+
+// Reduce returns a MiddleFunc that accumulates all the input items by means of the
+// reducer function, starting from the initial value, and forwards the accumulated
+// value once the input is closed.
+// If the node is restarted (see pipe.PanicRestartNode), the accumulation starts again from
+// the initial value. If the initial value is a pointer, a map or a slice, the reducer
+// should not modify it, as it would be shared by all the executions of the node.
+func Reduce[ACC, IN any](initial ACC, reducer func(ACC, IN) ACC) pipe.MiddleFunc[IN, ACC] {
+	return func(in <-chan IN, out chan<- ACC) {
+		acc := initial
+		for i := range in {
+			acc = reducer(acc, i)
+		}
+		out <- acc
+	}
+}
+
+// ReduceErr returns a MiddleFuncErr that accumulates all the input items by means of the
+// reducer function, starting from the initial value, and forwards the accumulated
+// value once the input is closed. If the reducer returns an error, nothing is forwarded.
+// See Reduce for more details.
+func ReduceErr[ACC, IN any](initial ACC, reducer func(ACC, IN) (ACC, error)) pipe.MiddleFuncErr[IN, ACC] {
+	return func(in <-chan IN, out chan<- ACC) error {
+		acc := initial
+		for i := range in {
+			var err error
+			if acc, err = reducer(acc, i); err != nil {
+				return err
+			}
+		}
+		out <- acc
+		return nil
+	}
+}
+
+// ForEach returns a FinalFunc that invokes the consumer function for each input item.
+func ForEach[IN any](consumer func(IN)) pipe.FinalFunc[IN] {
+	return func(in <-chan IN) {
+		for i := range in {
+			consumer(i)
+		}
+	}
+}
+
+// ForEachErr returns a FinalFuncErr that invokes the consumer function for each input item,
+// until the consumer returns an error.
+func ForEachErr[IN any](consumer func(IN) error) pipe.FinalFuncErr[IN] {
+	return func(in <-chan IN) error {
+		for i := range in {
+			if err := consumer(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
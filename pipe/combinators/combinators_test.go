@@ -0,0 +1,162 @@
+package combinators_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+)
+
+type testPipe[OUT any] struct {
+	numbers pipe.Start[int]
+	mid     pipe.Middle[int, OUT]
+	final   pipe.Final[OUT]
+}
+
+func (t *testPipe[OUT]) Connect() {
+	t.numbers.SendTo(t.mid)
+	t.mid.SendTo(t.final)
+}
+
+// run a pipeline that sends the numbers from 1 to 6 to the provided middle node,
+// and returns the collected output and the error of the Runner
+func run[OUT any](t *testing.T, mid func(*pipe.Builder[*testPipe[OUT]], pipe.MiddlePtr[*testPipe[OUT], int, OUT])) ([]OUT, error) {
+	b := pipe.NewBuilder(&testPipe[OUT]{})
+	pipe.AddStart(b, func(t *testPipe[OUT]) *pipe.Start[int] { return &t.numbers },
+		func(out chan<- int) {
+			for i := 1; i <= 6; i++ {
+				out <- i
+			}
+		})
+	mid(b, func(t *testPipe[OUT]) *pipe.Middle[int, OUT] { return &t.mid })
+	var collected []OUT
+	pipe.AddFinal(b, func(t *testPipe[OUT]) *pipe.Final[OUT] { return &t.final },
+		combinators.ForEach(func(o OUT) {
+			collected = append(collected, o)
+		}))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	err = r.Wait()
+	return collected, err
+}
+
+var errTooBig = errors.New("too big")
+
+func TestMap(t *testing.T) {
+	out, err := run(t, func(b *pipe.Builder[*testPipe[string]], mid pipe.MiddlePtr[*testPipe[string], int, string]) {
+		pipe.AddMiddle(b, mid, combinators.Map(strconv.Itoa))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6"}, out)
+
+	out, err = run(t, func(b *pipe.Builder[*testPipe[string]], mid pipe.MiddlePtr[*testPipe[string], int, string]) {
+		pipe.AddMiddleErr(b, mid, combinators.MapErr(func(i int) (string, error) {
+			if i > 3 {
+				return "", errTooBig
+			}
+			return strconv.Itoa(i), nil
+		}))
+	})
+	assert.ErrorIs(t, err, errTooBig)
+	assert.Equal(t, []string{"1", "2", "3"}, out)
+}
+
+func TestFilter(t *testing.T) {
+	out, err := run(t, func(b *pipe.Builder[*testPipe[int]], mid pipe.MiddlePtr[*testPipe[int], int, int]) {
+		pipe.AddMiddleProvider(b, mid, func() (pipe.MiddleFunc[int, int], error) {
+			return combinators.Filter(func(i int) bool { return i%2 == 0 }), nil
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 4, 6}, out)
+
+	out, err = run(t, func(b *pipe.Builder[*testPipe[int]], mid pipe.MiddlePtr[*testPipe[int], int, int]) {
+		pipe.AddMiddleErr(b, mid, combinators.FilterErr(func(i int) (bool, error) {
+			if i > 4 {
+				return false, errTooBig
+			}
+			return i%2 == 1, nil
+		}))
+	})
+	assert.ErrorIs(t, err, errTooBig)
+	assert.Equal(t, []int{1, 3}, out)
+}
+
+func TestFlatMap(t *testing.T) {
+	out, err := run(t, func(b *pipe.Builder[*testPipe[string]], mid pipe.MiddlePtr[*testPipe[string], int, string]) {
+		pipe.AddMiddle(b, mid, combinators.FlatMap(func(i int) []string {
+			return strings.Split(strings.Repeat("x", i%3), "")
+		}))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "x", "x", "x", "x", "x"}, out)
+
+	out, err = run(t, func(b *pipe.Builder[*testPipe[string]], mid pipe.MiddlePtr[*testPipe[string], int, string]) {
+		pipe.AddMiddleErr(b, mid, combinators.FlatMapErr(func(i int) ([]string, error) {
+			if i > 2 {
+				return []string{"ignored"}, errTooBig
+			}
+			return []string{strconv.Itoa(i), strconv.Itoa(-i)}, nil
+		}))
+	})
+	assert.ErrorIs(t, err, errTooBig)
+	assert.Equal(t, []string{"1", "-1", "2", "-2"}, out)
+}
+
+func TestReduce(t *testing.T) {
+	out, err := run(t, func(b *pipe.Builder[*testPipe[int]], mid pipe.MiddlePtr[*testPipe[int], int, int]) {
+		pipe.AddMiddle(b, mid, combinators.Reduce(0, func(acc, i int) int { return acc + i }))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{21}, out)
+
+	out, err = run(t, func(b *pipe.Builder[*testPipe[int]], mid pipe.MiddlePtr[*testPipe[int], int, int]) {
+		pipe.AddMiddleErr(b, mid, combinators.ReduceErr(0, func(acc, i int) (int, error) {
+			if acc > 5 {
+				return acc, errTooBig
+			}
+			return acc + i, nil
+		}))
+	})
+	assert.ErrorIs(t, err, errTooBig)
+	assert.Empty(t, out)
+}
+
+func TestForEachErr(t *testing.T) {
+	out, err := run(t, func(b *pipe.Builder[*testPipe[int]], mid pipe.MiddlePtr[*testPipe[int], int, int]) {
+		pipe.AddMiddle(b, mid, combinators.Map(func(i int) int { return i * 10 }))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60}, out)
+
+	var consumed []int
+	b := pipe.NewBuilder(&testPipe[int]{})
+	pipe.AddStart(b, func(t *testPipe[int]) *pipe.Start[int] { return &t.numbers },
+		func(out chan<- int) {
+			for i := 1; i <= 6; i++ {
+				out <- i
+			}
+		})
+	pipe.AddMiddle(b, func(t *testPipe[int]) *pipe.Middle[int, int] { return &t.mid },
+		combinators.Filter(func(int) bool { return true }))
+	pipe.AddFinalErr(b, func(t *testPipe[int]) *pipe.Final[int] { return &t.final },
+		combinators.ForEachErr(func(i int) error {
+			if i == 3 {
+				return errTooBig
+			}
+			consumed = append(consumed, i)
+			return nil
+		}))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	assert.ErrorIs(t, r.Wait(), errTooBig)
+	assert.Equal(t, []int{1, 2}, consumed)
+}
@@ -0,0 +1,219 @@
+package combinators
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// DedupSet remembers the keys that have been already seen by a Dedup node.
+// The LRUSet, TTLSet and BloomSet functions create the provided implementations, but
+// any other type implementing it can be used. A DedupSet is only accessed by the
+// goroutine of its Dedup node, so it does not need to be thread-safe.
+type DedupSet[K comparable] interface {
+	// Seen adds the key to the set and returns whether it was already there
+	Seen(key K) bool
+}
+
+// DedupCounters counts the results of the lookups of a Dedup node in its DedupSet.
+// It can be shared by multiple nodes.
+type DedupCounters struct {
+	hits   uint64
+	misses uint64
+}
+
+// Hits returns the number of items that have been discarded as duplicates.
+func (c *DedupCounters) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns the number of items that have been forwarded because their key
+// had not been seen before.
+func (c *DedupCounters) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+// Dedup returns a MiddleFunc that discards the input items whose key has already been
+// seen, according to the provided DedupSet. Each invocation of the returned function (e.g.
+// after a restart, see pipe.PanicRestartNode) starts with an empty set, which is created by
+// the newSet function.
+// If counters is not nil, it counts the discarded (hits) and forwarded (misses) items.
+func Dedup[T any, K comparable](key func(T) K, newSet func() DedupSet[K], counters *DedupCounters) pipe.MiddleFunc[T, T] {
+	return func(in <-chan T, out chan<- T) {
+		set := newSet()
+		for i := range in {
+			if set.Seen(key(i)) {
+				if counters != nil {
+					atomic.AddUint64(&counters.hits, 1)
+				}
+				continue
+			}
+			if counters != nil {
+				atomic.AddUint64(&counters.misses, 1)
+			}
+			out <- i
+		}
+	}
+}
+
+// LRUSet returns a function that creates a DedupSet remembering the last capacity keys that
+// have been seen. When the set is full, the least recently seen key is forgotten.
+// Capacity values lower than 1 are increased up to 1.
+func LRUSet[K comparable](capacity int) func() DedupSet[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return func() DedupSet[K] {
+		return &lruSet[K]{capacity: capacity, entries: map[K]*list.Element{}, order: list.New()}
+	}
+}
+
+type lruSet[K comparable] struct {
+	capacity int
+	entries  map[K]*list.Element
+	// order of the keys, from the most to the least recently seen
+	order *list.List
+}
+
+func (s *lruSet[K]) Seen(key K) bool {
+	if e, ok := s.entries[key]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(K))
+	}
+	s.entries[key] = s.order.PushFront(key)
+	return false
+}
+
+// TTLSet returns a function that creates a DedupSet remembering each key during the ttl period
+// after it was first seen, up to capacity keys. When the set is full, the oldest key is forgotten
+// before its ttl expires. Capacity values lower than 1 are increased up to 1.
+// The time is provided by the Clock (see WithClock).
+func TTLSet[K comparable](ttl time.Duration, capacity int, opts ...Option) func() DedupSet[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	clock := getOptions(opts...).clock
+	return func() DedupSet[K] {
+		return &ttlSet[K]{ttl: ttl, capacity: capacity, clock: clock, entries: map[K]*list.Element{}, order: list.New()}
+	}
+}
+
+type ttlEntry[K comparable] struct {
+	key     K
+	expires time.Time
+}
+
+type ttlSet[K comparable] struct {
+	ttl      time.Duration
+	capacity int
+	clock    Clock
+	entries  map[K]*list.Element
+	// order of the keys, from the newest to the oldest
+	order *list.List
+}
+
+func (s *ttlSet[K]) Seen(key K) bool {
+	now := s.clock.Now()
+	// forget the expired keys
+	for oldest := s.order.Back(); oldest != nil && !oldest.Value.(ttlEntry[K]).expires.After(now); oldest = s.order.Back() {
+		s.remove(oldest)
+	}
+	if _, ok := s.entries[key]; ok {
+		return true
+	}
+	if s.order.Len() >= s.capacity {
+		s.remove(s.order.Back())
+	}
+	s.entries[key] = s.order.PushFront(ttlEntry[K]{key: key, expires: now.Add(s.ttl)})
+	return false
+}
+
+func (s *ttlSet[K]) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(ttlEntry[K]).key)
+}
+
+// BloomSet returns a function that creates a probabilistic DedupSet, backed by a Bloom filter,
+// that requires a fixed amount of memory for very high cardinality streams.
+// The filter is sized to remember the given number of keys with the given false positive
+// probability (e.g. 0.01). False positives make the Dedup node discard items whose key has not
+// been seen before. Once the filter has remembered the given number of keys, it is reset, to
+// avoid that the false positive probability keeps growing, so some duplicates could be forwarded.
+// The hash function must write the key into the provided maphash.Hash (see StringHash).
+func BloomSet[K comparable](keys int, falsePositiveRate float64, hash func(*maphash.Hash, K)) func() DedupSet[K] {
+	if keys < 1 {
+		keys = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	// optimal number of bits and hash functions
+	bits := uint64(math.Ceil(-float64(keys) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Ceil(float64(bits) / float64(keys) * math.Ln2))
+	return func() DedupSet[K] {
+		return &bloomSet[K]{
+			bits:     make([]uint64, (bits+63)/64),
+			size:     bits,
+			hashes:   hashes,
+			capacity: keys,
+			hash:     hash,
+			seed:     maphash.MakeSeed(),
+		}
+	}
+}
+
+type bloomSet[K comparable] struct {
+	bits     []uint64
+	size     uint64
+	hashes   int
+	capacity int
+	count    int
+	hash     func(*maphash.Hash, K)
+	seed     maphash.Seed
+}
+
+func (s *bloomSet[K]) Seen(key K) bool {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	s.hash(&h, key)
+	sum := h.Sum64()
+	// double hashing to get the positions of the k hash functions
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	found := true
+	for i := 0; i < s.hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % s.size
+		if s.bits[pos/64]&(1<<(pos%64)) == 0 {
+			found = false
+			break
+		}
+	}
+	if found {
+		return true
+	}
+	if s.count >= s.capacity {
+		for i := range s.bits {
+			s.bits[i] = 0
+		}
+		s.count = 0
+	}
+	for i := 0; i < s.hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % s.size
+		s.bits[pos/64] |= 1 << (pos % 64)
+	}
+	s.count++
+	return false
+}
+
+// StringHash is a BloomSet hash function for string keys.
+func StringHash(h *maphash.Hash, key string) {
+	_, _ = h.WriteString(key)
+}
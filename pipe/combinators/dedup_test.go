@@ -0,0 +1,95 @@
+package combinators_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mariomac/pipes/pipe/combinators"
+)
+
+// dedup sends the keys to a Dedup node and returns the forwarded keys
+func dedup(node func(in <-chan string, out chan<- string), keys ...string) []string {
+	in, out := make(chan string), make(chan string, len(keys))
+	go func() {
+		node(in, out)
+		close(out)
+	}()
+	for _, k := range keys {
+		in <- k
+	}
+	close(in)
+	var forwarded []string
+	for k := range out {
+		forwarded = append(forwarded, k)
+	}
+	return forwarded
+}
+
+func identity(s string) string { return s }
+
+func TestDedup_LRU(t *testing.T) {
+	counters := &combinators.DedupCounters{}
+	node := combinators.Dedup(identity, combinators.LRUSet[string](2), counters)
+	// when "c" is added, "b" is the least recently seen key
+	assert.Equal(t, []string{"a", "b", "c", "b"}, dedup(node, "a", "b", "a", "c", "a", "b"))
+	assert.EqualValues(t, 2, counters.Hits())
+	assert.EqualValues(t, 4, counters.Misses())
+}
+
+func TestDedup_TTL(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	counters := &combinators.DedupCounters{}
+	set := combinators.TTLSet[string](time.Minute, 2, combinators.WithClock(clock))()
+	node := combinators.Dedup(identity, func() combinators.DedupSet[string] { return set }, counters)
+
+	assert.Equal(t, []string{"a", "b"}, dedup(node, "a", "b", "a", "b"))
+	clock.Advance(30 * time.Second)
+	// "a" is the oldest key, so it is forgotten when "c" is added
+	assert.Equal(t, []string{"c", "a"}, dedup(node, "b", "c", "a"))
+	clock.Advance(time.Minute)
+	// "c" and "a" expired
+	assert.Equal(t, []string{"a", "c", "b"}, dedup(node, "a", "c", "a", "b"))
+	assert.EqualValues(t, 4, counters.Hits())
+	assert.EqualValues(t, 7, counters.Misses())
+}
+
+func TestDedup_Bloom(t *testing.T) {
+	counters := &combinators.DedupCounters{}
+	node := combinators.Dedup(identity, combinators.BloomSet[string](10000, 0.01, combinators.StringHash), counters)
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, strconv.Itoa(i), strconv.Itoa(i))
+	}
+	forwarded := dedup(node, keys...)
+	// false positives can discard some non-duplicate keys
+	assert.GreaterOrEqual(t, len(forwarded), 980)
+	assert.LessOrEqual(t, len(forwarded), 1000)
+	assert.EqualValues(t, len(forwarded), counters.Misses())
+	assert.EqualValues(t, 2000-len(forwarded), counters.Hits())
+}
+
+// caseInsensitiveSet is a custom DedupSet that ignores the case of the keys
+type caseInsensitiveSet map[string]struct{}
+
+func (s caseInsensitiveSet) Seen(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := s[key]; ok {
+		return true
+	}
+	s[key] = struct{}{}
+	return false
+}
+
+func TestDedup_CustomSet(t *testing.T) {
+	counters := &combinators.DedupCounters{}
+	node := combinators.Dedup(identity, func() combinators.DedupSet[string] {
+		return caseInsensitiveSet{}
+	}, counters)
+	assert.Equal(t, []string{"a", "B"}, dedup(node, "a", "B", "A", "b"))
+	assert.EqualValues(t, 2, counters.Hits())
+	assert.EqualValues(t, 2, counters.Misses())
+}
@@ -0,0 +1,140 @@
+package combinators
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// Limiter is a token bucket that limits the throughput of the RateLimit and Throttle nodes.
+// The bucket is refilled at a constant rate of tokens per second, up to its burst size,
+// and each forwarded item takes a token from the bucket.
+// A Limiter can be shared by multiple nodes, so they draw from a common budget.
+type Limiter struct {
+	mt     sync.Mutex
+	clock  Clock
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	dropped uint64
+}
+
+// NewLimiter creates a Limiter that allows ratePerSecond items per second on average, and
+// bursts of up to burst items. The bucket is initially full. Burst values lower than 1 are
+// increased up to 1. A ratePerSecond lower or equal than zero does not limit the throughput.
+func NewLimiter(ratePerSecond float64, burst int, opts ...Option) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	clock := getOptions(opts...).clock
+	return &Limiter{
+		clock:  clock,
+		rate:   ratePerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// refill must be invoked with the mutex locked
+func (l *Limiter) refill() {
+	now := l.clock.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// Allow takes a token from the bucket, if available, and returns whether it could be taken.
+func (l *Limiter) Allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait takes a token from the bucket, blocking until it is available. The tokens are
+// granted in the same order as the Wait invocations.
+// If the context is cancelled before the token is available, Wait returns the error of the
+// context and the reserved token is returned to the bucket.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.mt.Lock()
+	l.refill()
+	// the token is reserved in advance, so the bucket can go negative until it is refilled
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mt.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := l.clock.NewTimer(wait)
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		l.mt.Lock()
+		l.refill()
+		l.tokens++
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.mt.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of items that have been discarded by the Throttle nodes
+// that use this Limiter.
+func (l *Limiter) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// RateLimit returns a MiddleFuncCtx that forwards the input items as fast as the limiter
+// allows, delaying them when the limiter runs out of tokens. Once the context of the node
+// is cancelled (e.g. by pipe.Runner.Stop), the rest of the input items are forwarded without
+// delay, so the pipeline is drained.
+func RateLimit[T any](limiter *Limiter) pipe.MiddleFuncCtx[T, T] {
+	return func(ctx context.Context, in <-chan T, out chan<- T) {
+		for i := range in {
+			// the error is ignored, as the item is forwarded anyway
+			_ = limiter.Wait(ctx)
+			out <- i
+		}
+	}
+}
+
+// Throttle returns a MiddleFuncCtx that forwards the input items as fast as the limiter
+// allows, discarding them when the limiter runs out of tokens. The discarded items
+// are counted by the Limiter.Dropped method. As it never waits for the limiter, the
+// context of the node is ignored.
+func Throttle[T any](limiter *Limiter) pipe.MiddleFuncCtx[T, T] {
+	return func(_ context.Context, in <-chan T, out chan<- T) {
+		for i := range in {
+			if limiter.Allow() {
+				out <- i
+			} else {
+				atomic.AddUint64(&limiter.dropped, 1)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package combinators_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	l := combinators.NewLimiter(2, 3, combinators.WithClock(clock))
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	// the bucket does not exceed the burst size
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow())
+	}
+	assert.False(t, l.Allow())
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	l := combinators.NewLimiter(1, 2, combinators.WithClock(clock))
+	in, out := make(chan int, 10), make(chan int, 10)
+	go combinators.RateLimit[int](l)(context.Background(), in, out)
+	for i := 1; i <= 4; i++ {
+		in <- i
+	}
+	assert.Equal(t, 1, helpers.ReadChannel(t, out, timeout))
+	assert.Equal(t, 2, helpers.ReadChannel(t, out, timeout))
+	// the third item waits for a token
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+	assert.Empty(t, out)
+	clock.Advance(time.Second)
+	assert.Equal(t, 3, helpers.ReadChannel(t, out, timeout))
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+	clock.Advance(time.Second)
+	assert.Equal(t, 4, helpers.ReadChannel(t, out, timeout))
+	close(in)
+}
+
+func TestLimiter_WaitCancel(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	l := combinators.NewLimiter(1, 1, combinators.WithClock(clock))
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- l.Wait(ctx) }()
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, helpers.ReadChannel(t, waitErr, timeout), context.Canceled)
+	// the timer is stopped and the reserved token is returned to the bucket
+	assert.Zero(t, clock.Timers())
+	assert.False(t, l.Allow())
+	clock.Advance(time.Second)
+	assert.True(t, l.Allow())
+
+	// an already cancelled context does not take any token
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+	clock.Advance(time.Second)
+	assert.True(t, l.Allow())
+}
+
+type rateLimitPipe struct {
+	start   pipe.Start[int]
+	limiter pipe.Middle[int, int]
+	final   pipe.Final[int]
+}
+
+func (p *rateLimitPipe) Connect() {
+	p.start.SendTo(p.limiter)
+	p.limiter.SendTo(p.final)
+}
+
+func TestRateLimit_Stop(t *testing.T) {
+	// the clock never advances, so the limiter would block forever after the burst
+	clock := combinators.NewManualClock(time.Now())
+	l := combinators.NewLimiter(1, 2, combinators.WithClock(clock))
+	b := pipe.NewBuilder(&rateLimitPipe{})
+	pipe.AddStartCtx(b, func(p *rateLimitPipe) *pipe.Start[int] { return &p.start },
+		func(ctx context.Context, out chan<- int) {
+			for i := 1; i <= 4; i++ {
+				out <- i
+			}
+			<-ctx.Done()
+		})
+	pipe.AddMiddleCtx(b, func(p *rateLimitPipe) *pipe.Middle[int, int] { return &p.limiter }, combinators.RateLimit[int](l))
+	forwarded := make(chan int, 10)
+	pipe.AddFinal(b, func(p *rateLimitPipe) *pipe.Final[int] { return &p.final }, func(in <-chan int) {
+		for i := range in {
+			forwarded <- i
+		}
+	})
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	assert.Equal(t, 1, helpers.ReadChannel(t, forwarded, timeout))
+	assert.Equal(t, 2, helpers.ReadChannel(t, forwarded, timeout))
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, timeout, time.Millisecond)
+
+	// stopping the pipeline unblocks the limiter, and the rest of the items are drained
+	r.Stop()
+	assert.Equal(t, 3, helpers.ReadChannel(t, forwarded, timeout))
+	assert.Equal(t, 4, helpers.ReadChannel(t, forwarded, timeout))
+	require.NoError(t, r.Wait())
+}
+
+type throttlePipe struct {
+	startA    pipe.Start[int]
+	startB    pipe.Start[int]
+	throttleA pipe.Middle[int, int]
+	throttleB pipe.Middle[int, int]
+	final     pipe.Final[int]
+}
+
+func (p *throttlePipe) Connect() {
+	p.startA.SendTo(p.throttleA)
+	p.startB.SendTo(p.throttleB)
+	p.throttleA.SendTo(p.final)
+	p.throttleB.SendTo(p.final)
+}
+
+func TestThrottle_SharedLimiter(t *testing.T) {
+	// the clock never advances, so only the burst is allowed
+	clock := combinators.NewManualClock(time.Now())
+	l := combinators.NewLimiter(10, 5, combinators.WithClock(clock))
+	counter := func(out chan<- int) {
+		for i := 0; i < 10; i++ {
+			out <- i
+		}
+	}
+	b := pipe.NewBuilder(&throttlePipe{})
+	pipe.AddStart(b, func(p *throttlePipe) *pipe.Start[int] { return &p.startA }, counter)
+	pipe.AddStart(b, func(p *throttlePipe) *pipe.Start[int] { return &p.startB }, counter)
+	pipe.AddMiddleCtx(b, func(p *throttlePipe) *pipe.Middle[int, int] { return &p.throttleA }, combinators.Throttle[int](l))
+	pipe.AddMiddleCtx(b, func(p *throttlePipe) *pipe.Middle[int, int] { return &p.throttleB }, combinators.Throttle[int](l))
+	forwarded := 0
+	pipe.AddFinal(b, func(p *throttlePipe) *pipe.Final[int] { return &p.final },
+		combinators.ForEach(func(int) { forwarded++ }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	require.NoError(t, r.Wait())
+
+	assert.Equal(t, 5, forwarded)
+	assert.EqualValues(t, 15, l.Dropped())
+}
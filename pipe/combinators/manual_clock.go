@@ -0,0 +1,79 @@
+package combinators
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a Clock whose time only moves forward when the Advance method
+// is invoked, so the time-based combinators can be deterministically tested.
+type ManualClock struct {
+	mt     sync.Mutex
+	now    time.Time
+	timers map[*manualTimer]struct{}
+}
+
+// NewManualClock creates a ManualClock whose current time is the provided instant.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now, timers: map[*manualTimer]struct{}{}}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires when the clock is advanced by at least d.
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	t := &manualTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers[t] = struct{}{}
+	return t
+}
+
+// Advance moves the current time of the clock forward, firing all the timers
+// whose deadline has been reached.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.deadline.After(c.now) {
+			t.c <- c.now
+			delete(c.timers, t)
+		}
+	}
+}
+
+// Timers returns the number of timers that haven't still fired nor been stopped.
+// It allows tests to wait for a node to create its timers before advancing the clock.
+func (c *ManualClock) Timers() int {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	return len(c.timers)
+}
+
+type manualTimer struct {
+	clock    *ManualClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mt.Lock()
+	defer t.clock.mt.Unlock()
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return pending
+}
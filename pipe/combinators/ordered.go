@@ -0,0 +1,112 @@
+package combinators
+
+import "github.com/mariomac/pipes/pipe"
+
+// OrderedMap returns a MiddleFunc that applies the mapper function to each input item
+// in a pool of parallel worker goroutines, and forwards the results in the same order as
+// their respective input items.
+//
+// The results that are ready but can't still be forwarded, because the result of a previous
+// item is still being calculated, are kept in a reorder buffer of reorderBuffer items.
+// When the buffer is full, the node stops receiving new items until the oldest item is
+// forwarded. In total, the node keeps in memory at most reorderBuffer + 2 items, between
+// the items being processed, the results waiting in the buffer, the item waiting for a free
+// slot in the buffer and the result that is being forwarded.
+// Since the items being processed are also part of the reorder buffer, values of
+// reorderBuffer lower than workers are increased up to workers.
+//
+// If the mapper function panics, the panic is propagated to the goroutine of the node, so
+// it can be handled according to the pipe.PanicPolicy of the node. Any item that is being
+// processed in parallel is discarded. If the node is restarted, the restarted function
+// receives the rest of the input.
+func OrderedMap[IN, OUT any](workers, reorderBuffer int, mapper func(IN) OUT) pipe.MiddleFunc[IN, OUT] {
+	if workers < 1 {
+		workers = 1
+	}
+	if reorderBuffer < workers {
+		reorderBuffer = workers
+	}
+	return func(in <-chan IN, out chan<- OUT) {
+		// order keeps the pending results in the same order as the input items
+		order := make(chan chan mapResult[OUT], reorderBuffer)
+		jobs := make(chan mapJob[IN, OUT])
+		done := make(chan struct{})
+		dispatched := make(chan struct{})
+		// the dispatcher must stop reading the input before the function returns, as the
+		// input is still read by the function if it is restarted
+		defer func() {
+			close(done)
+			<-dispatched
+		}()
+		go func() {
+			dispatchOrdered(in, order, jobs, done)
+			close(dispatched)
+		}()
+		for w := 0; w < workers; w++ {
+			go func() {
+				for job := range jobs {
+					job.result <- mapItem(mapper, job.item)
+				}
+			}()
+		}
+		for pending := range order {
+			result := <-pending
+			if result.panicked {
+				panic(result.panicValue)
+			}
+			out <- result.out
+		}
+	}
+}
+
+type mapJob[IN, OUT any] struct {
+	item   IN
+	result chan<- mapResult[OUT]
+}
+
+type mapResult[OUT any] struct {
+	out        OUT
+	panicked   bool
+	panicValue any
+}
+
+// dispatchOrdered reserves a slot of the reorder buffer for each input item, then sends it
+// to the workers. It returns when the input is closed or the node function returned.
+func dispatchOrdered[IN, OUT any](
+	in <-chan IN, order chan<- chan mapResult[OUT], jobs chan<- mapJob[IN, OUT], done <-chan struct{},
+) {
+	defer close(order)
+	defer close(jobs)
+	for {
+		var item IN
+		select {
+		case i, ok := <-in:
+			if !ok {
+				return
+			}
+			item = i
+		case <-done:
+			return
+		}
+		result := make(chan mapResult[OUT], 1)
+		select {
+		case order <- result:
+		case <-done:
+			return
+		}
+		select {
+		case jobs <- mapJob[IN, OUT]{item: item, result: result}:
+		case <-done:
+			return
+		}
+	}
+}
+
+func mapItem[IN, OUT any](mapper func(IN) OUT, item IN) (result mapResult[OUT]) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = mapResult[OUT]{panicked: true, panicValue: r}
+		}
+	}()
+	return mapResult[OUT]{out: mapper(item)}
+}
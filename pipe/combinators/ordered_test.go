@@ -0,0 +1,125 @@
+package combinators_test
+
+import (
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+)
+
+type orderedPipe struct {
+	numbers pipe.Start[int]
+	mapper  pipe.Middle[int, int]
+	final   pipe.Final[int]
+}
+
+func (p *orderedPipe) Connect() {
+	p.numbers.SendTo(p.mapper)
+	p.mapper.SendTo(p.final)
+}
+
+// runOrdered runs a pipeline where the provided start function sends its items to the
+// middle function, and returns the collected output and the error of the Runner
+func runOrdered(
+	t *testing.T, start pipe.StartFunc[int], mid pipe.MiddleFunc[int, int], opts ...pipe.Option,
+) ([]int, error) {
+	b := pipe.NewBuilder(&orderedPipe{})
+	pipe.AddStart(b, func(p *orderedPipe) *pipe.Start[int] { return &p.numbers }, start)
+	pipe.AddMiddle(b, func(p *orderedPipe) *pipe.Middle[int, int] { return &p.mapper }, mid, opts...)
+	var collected []int
+	pipe.AddFinal(b, func(p *orderedPipe) *pipe.Final[int] { return &p.final }, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	err = r.Wait()
+	return collected, err
+}
+
+func counter(from, to int) pipe.StartFunc[int] {
+	return func(out chan<- int) {
+		for i := from; i <= to; i++ {
+			out <- i
+		}
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	collected, err := runOrdered(t, counter(1, 50), combinators.OrderedMap(4, 8, func(i int) int {
+		// later items are processed faster
+		time.Sleep(time.Duration(50-i) * 100 * time.Microsecond)
+		return i * 10
+	}))
+	require.NoError(t, err)
+
+	require.Len(t, collected, 50)
+	for i, n := range collected {
+		assert.Equal(t, (i+1)*10, n)
+	}
+}
+
+func TestOrderedMap_BoundedBuffer(t *testing.T) {
+	var sent int32
+	unblock := make(chan struct{})
+	go func() {
+		// while the first item is blocked, the node only accepts the blocked item, the items
+		// that fit in the reorder buffer, and the item that waits for a free slot
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(7), atomic.LoadInt32(&sent))
+		close(unblock)
+	}()
+	collected, err := runOrdered(t, func(out chan<- int) {
+		for i := 1; i <= 100; i++ {
+			out <- i
+			atomic.AddInt32(&sent, 1)
+		}
+	}, combinators.OrderedMap(2, 5, func(i int) int {
+		if i == 1 {
+			<-unblock
+		}
+		return i
+	}))
+	require.NoError(t, err)
+	assert.Len(t, collected, 100)
+	assert.True(t, sort.IntsAreSorted(collected))
+}
+
+func TestOrderedMap_Panic(t *testing.T) {
+	collected, err := runOrdered(t, counter(1, 10), combinators.OrderedMap(3, 3, func(i int) int {
+		if i == 5 {
+			panic("five!")
+		}
+		return i
+	}), pipe.OnPanic(pipe.PanicStopPipeline))
+	assert.Equal(t, []int{1, 2, 3, 4}, collected)
+	var panicErr *pipe.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "five!", panicErr.Value)
+}
+
+func TestOrderedMap_PanicRestart(t *testing.T) {
+	collected, err := runOrdered(t, func(out chan<- int) {
+		counter(1, 5)(out)
+		// the rest of the items are sent after the node has been restarted
+		time.Sleep(50 * time.Millisecond)
+		counter(6, 10)(out)
+	}, combinators.OrderedMap(2, 2, func(i int) int {
+		if i == 5 {
+			panic("five!")
+		}
+		return i
+	}), pipe.OnPanic(pipe.PanicRestartNode))
+	// the function that panicked doesn't take any item from the restarted function
+	assert.Equal(t, []int{1, 2, 3, 4, 6, 7, 8, 9, 10}, collected)
+	var panicErr *pipe.PanicError
+	require.ErrorAs(t, err, &panicErr)
+}
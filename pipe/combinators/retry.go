@@ -0,0 +1,277 @@
+package combinators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// Names of the outputs of the Retry node, to be declared with the pipe.DemuxAdd function.
+const (
+	// RetryOutput forwards the results of the items that have been successfully processed.
+	RetryOutput = "out"
+	// DeadLetterOutput forwards, as DeadLetter instances, the items that couldn't be processed.
+	DeadLetterOutput = "deadLetter"
+)
+
+// ErrCircuitOpen is the error of the attempts that are rejected by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy configures the Retry node.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times that an item is processed before it is
+	// sent to the dead-letter output. Values lower than 1 are increased up to 1.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff limits the time between attempts. Zero means no limit.
+	MaxBackoff time.Duration
+	// Multiplier of the backoff time after each attempt. Values lower than 1 are increased up to 2.
+	Multiplier float64
+	// AttemptTimeout, if not zero, cancels the context that is passed to each attempt after
+	// the given time. The processing function is responsible for returning when its context is done.
+	AttemptTimeout time.Duration
+	// Breaker, if not nil, rejects the attempts while it is open. The rejected attempts fail
+	// with ErrCircuitOpen. A CircuitBreaker can be shared by multiple nodes calling the same backend.
+	Breaker *CircuitBreaker
+}
+
+// DeadLetter is an item that couldn't be processed by a Retry node.
+type DeadLetter[IN any] struct {
+	Item IN
+	// Err is the error of the last attempt
+	Err      error
+	Attempts int
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent wraps an error to tell the Retry node that the item must not be retried,
+// and it has to be sent to the dead-letter output.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Retry returns a MiddleDemuxFuncCtx that forwards, through its RetryOutput, the results of
+// applying the process function to each input item. If the function returns an error, the item
+// is retried according to the policy, with exponential backoff. The items that can't be processed
+// after the maximum number of attempts, or whose error has been wrapped by the Permanent function,
+// are forwarded as DeadLetter[IN] instances through the DeadLetterOutput.
+// If the DeadLetterOutput hasn't been declared, the failed items are discarded and the error
+// of the undeclared output is reported to the pipe.Runner.
+// The context of each attempt is derived from the context of the node, so the attempts are
+// cancelled when the pipeline is stopped (see pipe.Runner.Stop). Then, the failed items are
+// forwarded through the DeadLetterOutput without waiting for the backoff nor retrying them.
+// The backoff time is measured by the Clock (see WithClock).
+func Retry[IN, OUT any](process func(context.Context, IN) (OUT, error), policy RetryPolicy, opts ...Option) pipe.MiddleDemuxFuncCtx[IN] {
+	clock := getOptions(opts...).clock
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	return func(ctx context.Context, in <-chan IN, out pipe.Demux) {
+		results := pipe.DemuxGet[OUT](out, RetryOutput)
+		var deadLetters chan<- DeadLetter[IN]
+		for item := range in {
+			result, dl := retry(ctx, clock, &policy, process, item)
+			if dl == nil {
+				results <- result
+				continue
+			}
+			if deadLetters == nil {
+				// the dead letter output is optional, so it is only accessed on failure
+				deadLetters = pipe.DemuxGet[DeadLetter[IN]](out, DeadLetterOutput)
+			}
+			deadLetters <- *dl
+		}
+	}
+}
+
+func retry[IN, OUT any](
+	ctx context.Context, clock Clock, policy *RetryPolicy, process func(context.Context, IN) (OUT, error), item IN,
+) (OUT, *DeadLetter[IN]) {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := attemptProcess(ctx, policy, process, item)
+		if err == nil {
+			return result, nil
+		}
+		var permanent permanentError
+		if attempt >= policy.MaxAttempts || errors.As(err, &permanent) || ctx.Err() != nil {
+			return result, &DeadLetter[IN]{Item: item, Err: err, Attempts: attempt}
+		}
+		if backoff > 0 {
+			timer := clock.NewTimer(backoff)
+			select {
+			case <-timer.C():
+			case <-ctx.Done():
+				timer.Stop()
+				return result, &DeadLetter[IN]{Item: item, Err: err, Attempts: attempt}
+			}
+		}
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+func attemptProcess[IN, OUT any](
+	ctx context.Context, policy *RetryPolicy, process func(context.Context, IN) (OUT, error), item IN,
+) (OUT, error) {
+	if policy.Breaker != nil && !policy.Breaker.allow() {
+		var zero OUT
+		return zero, ErrCircuitOpen
+	}
+	if policy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		defer cancel()
+	}
+	result, err := process(ctx, item)
+	if policy.Breaker != nil {
+		policy.Breaker.record(err == nil)
+	}
+	return result, err
+}
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed allows all the attempts.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all the attempts.
+	BreakerOpen
+	// BreakerHalfOpen allows a single trial attempt, whose result decides whether the
+	// breaker is closed again or reopened.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker stops calling a failing backend during some time, to give it the opportunity
+// to recover. The breaker is opened after a number of consecutive failed attempts. After the open
+// timeout, the breaker is half-open and allows a trial attempt: if it succeeds the breaker is
+// closed, otherwise it is opened again.
+type CircuitBreaker struct {
+	mt               sync.Mutex
+	clock            Clock
+	failureThreshold int
+	openTimeout      time.Duration
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	trialInFlight    bool
+	onChange         func(from, to BreakerState)
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that is opened after failureThreshold consecutive
+// failures, during openTimeout. Threshold values lower than 1 are increased up to 1.
+// The open timeout is measured by the Clock (see WithClock).
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration, opts ...Option) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		clock:            getOptions(opts...).clock,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mt.Lock()
+	defer b.mt.Unlock()
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openedAt.Add(b.openTimeout)) {
+		b.setState(BreakerHalfOpen)
+	}
+	return b.state
+}
+
+// OnStateChange registers a function that is invoked on each state change of the breaker.
+// The function must not invoke any method of the breaker.
+func (b *CircuitBreaker) OnStateChange(fn func(from, to BreakerState)) {
+	b.mt.Lock()
+	defer b.mt.Unlock()
+	b.onChange = fn
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mt.Lock()
+	defer b.mt.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.clock.Now().Before(b.openedAt.Add(b.openTimeout)) {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) record(success bool) {
+	b.mt.Lock()
+	defer b.mt.Unlock()
+	if success {
+		b.failures = 0
+		b.trialInFlight = false
+		b.setState(BreakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.trialInFlight = false
+		b.openedAt = b.clock.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// setState must be invoked with the mutex locked
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
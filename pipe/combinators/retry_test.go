@@ -0,0 +1,214 @@
+package combinators_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+)
+
+type retryPipe struct {
+	start pipe.Start[int]
+	call  pipe.MiddleDemux[int]
+	ok    pipe.Final[string]
+	dead  pipe.Final[combinators.DeadLetter[int]]
+}
+
+func (p *retryPipe) Connect() {
+	p.start.SendTo(p.call)
+	pipe.DemuxAdd[string](p.call, combinators.RetryOutput).SendTo(p.ok)
+	pipe.DemuxAdd[combinators.DeadLetter[int]](p.call, combinators.DeadLetterOutput).SendTo(p.dead)
+}
+
+var errBackend = errors.New("backend error")
+
+func TestRetry(t *testing.T) {
+	// items fail as many times as their value, negative items fail permanently
+	attempts := map[int]int{}
+	backend := func(_ context.Context, i int) (string, error) {
+		attempts[i]++
+		if i < 0 {
+			return "", combinators.Permanent(errBackend)
+		}
+		if attempts[i] <= i {
+			return "", errBackend
+		}
+		return "ok", nil
+	}
+	var oks []string
+	var deads []combinators.DeadLetter[int]
+	b := pipe.NewBuilder(&retryPipe{})
+	pipe.AddStart(b, func(p *retryPipe) *pipe.Start[int] { return &p.start }, func(out chan<- int) {
+		for _, i := range []int{0, 2, 5, -1} {
+			out <- i
+		}
+	})
+	pipe.AddMiddleDemuxCtx(b, func(p *retryPipe) *pipe.MiddleDemux[int] { return &p.call },
+		combinators.Retry(backend, combinators.RetryPolicy{
+			MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond,
+		}))
+	pipe.AddFinal(b, func(p *retryPipe) *pipe.Final[string] { return &p.ok },
+		combinators.ForEach(func(s string) { oks = append(oks, s) }))
+	pipe.AddFinal(b, func(p *retryPipe) *pipe.Final[combinators.DeadLetter[int]] { return &p.dead },
+		combinators.ForEach(func(d combinators.DeadLetter[int]) { deads = append(deads, d) }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	require.NoError(t, r.Wait())
+
+	assert.Equal(t, []string{"ok", "ok"}, oks)
+	require.Len(t, deads, 2)
+	assert.Equal(t, 5, deads[0].Item)
+	assert.Equal(t, 3, deads[0].Attempts)
+	assert.ErrorIs(t, deads[0].Err, errBackend)
+	assert.Equal(t, -1, deads[1].Item)
+	assert.Equal(t, 1, deads[1].Attempts)
+	assert.ErrorIs(t, deads[1].Err, errBackend)
+	assert.Equal(t, map[int]int{0: 1, 2: 3, 5: 3, -1: 1}, attempts)
+}
+
+func TestRetry_AttemptTimeout(t *testing.T) {
+	node := combinators.Retry(func(ctx context.Context, i int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, combinators.RetryPolicy{MaxAttempts: 2, AttemptTimeout: time.Millisecond})
+
+	b := pipe.NewBuilder(&retryIntPipe{})
+	pipe.AddStart(b, func(p *retryIntPipe) *pipe.Start[int] { return &p.start }, func(out chan<- int) { out <- 1 })
+	pipe.AddMiddleDemuxCtx(b, func(p *retryIntPipe) *pipe.MiddleDemux[int] { return &p.call }, node)
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[int] { return &p.ok }, combinators.ForEach(func(int) {
+		t.Error("no item should have been processed")
+	}))
+	var deads []combinators.DeadLetter[int]
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[combinators.DeadLetter[int]] { return &p.dead },
+		combinators.ForEach(func(d combinators.DeadLetter[int]) { deads = append(deads, d) }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	require.NoError(t, r.Wait())
+
+	require.Len(t, deads, 1)
+	assert.Equal(t, 2, deads[0].Attempts)
+	assert.ErrorIs(t, deads[0].Err, context.DeadlineExceeded)
+}
+
+func TestRetry_Stop(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	attempted := make(chan struct{}, 10)
+	node := combinators.Retry(func(_ context.Context, i int) (int, error) {
+		attempted <- struct{}{}
+		return 0, errBackend
+	}, combinators.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}, combinators.WithClock(clock))
+
+	b := pipe.NewBuilder(&retryIntPipe{})
+	pipe.AddStartCtx(b, func(p *retryIntPipe) *pipe.Start[int] { return &p.start }, func(ctx context.Context, out chan<- int) {
+		out <- 1
+		<-ctx.Done()
+	})
+	pipe.AddMiddleDemuxCtx(b, func(p *retryIntPipe) *pipe.MiddleDemux[int] { return &p.call }, node)
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[int] { return &p.ok }, combinators.ForEach(func(int) {
+		t.Error("no item should have been processed")
+	}))
+	var deads []combinators.DeadLetter[int]
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[combinators.DeadLetter[int]] { return &p.dead },
+		combinators.ForEach(func(d combinators.DeadLetter[int]) { deads = append(deads, d) }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+
+	// stopping the pipeline interrupts the backoff, which would never finish as the clock is not advanced
+	<-attempted
+	r.Stop()
+	require.NoError(t, r.Wait())
+
+	require.Len(t, deads, 1)
+	assert.Equal(t, 1, deads[0].Attempts)
+	assert.ErrorIs(t, deads[0].Err, errBackend)
+}
+
+type retryIntPipe struct {
+	start pipe.Start[int]
+	call  pipe.MiddleDemux[int]
+	ok    pipe.Final[int]
+	dead  pipe.Final[combinators.DeadLetter[int]]
+}
+
+func (p *retryIntPipe) Connect() {
+	p.start.SendTo(p.call)
+	pipe.DemuxAdd[int](p.call, combinators.RetryOutput).SendTo(p.ok)
+	pipe.DemuxAdd[combinators.DeadLetter[int]](p.call, combinators.DeadLetterOutput).SendTo(p.dead)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := combinators.NewManualClock(time.Now())
+	breaker := combinators.NewCircuitBreaker(2, time.Minute, combinators.WithClock(clock))
+	var transitions []string
+	breaker.OnStateChange(func(from, to combinators.BreakerState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+	fail := true
+	calls := 0
+	node := combinators.Retry(func(_ context.Context, i int) (int, error) {
+		calls++
+		if fail {
+			return 0, errBackend
+		}
+		return i, nil
+	}, combinators.RetryPolicy{Breaker: breaker})
+
+	in := make(chan int)
+	b := pipe.NewBuilder(&retryIntPipe{})
+	pipe.AddStart(b, func(p *retryIntPipe) *pipe.Start[int] { return &p.start }, func(out chan<- int) {
+		for i := range in {
+			out <- i
+		}
+	})
+	pipe.AddMiddleDemuxCtx(b, func(p *retryIntPipe) *pipe.MiddleDemux[int] { return &p.call }, node)
+	oks := make(chan int, 10)
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[int] { return &p.ok }, combinators.ForEach(func(i int) { oks <- i }))
+	deads := make(chan combinators.DeadLetter[int], 10)
+	pipe.AddFinal(b, func(p *retryIntPipe) *pipe.Final[combinators.DeadLetter[int]] { return &p.dead },
+		combinators.ForEach(func(d combinators.DeadLetter[int]) { deads <- d }))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+
+	// two consecutive failures open the breaker
+	in <- 1
+	in <- 2
+	assert.ErrorIs(t, (<-deads).Err, errBackend)
+	assert.ErrorIs(t, (<-deads).Err, errBackend)
+	assert.Equal(t, combinators.BreakerOpen, breaker.State())
+
+	// the backend is not invoked while the breaker is open
+	in <- 3
+	assert.ErrorIs(t, (<-deads).Err, combinators.ErrCircuitOpen)
+	assert.Equal(t, 2, calls)
+
+	// after the open timeout, a failed trial opens the breaker again
+	clock.Advance(time.Minute)
+	assert.Equal(t, combinators.BreakerHalfOpen, breaker.State())
+	in <- 4
+	assert.ErrorIs(t, (<-deads).Err, errBackend)
+	assert.Equal(t, combinators.BreakerOpen, breaker.State())
+
+	// a successful trial closes the breaker
+	clock.Advance(time.Minute)
+	fail = false
+	in <- 5
+	assert.Equal(t, 5, <-oks)
+	assert.Equal(t, combinators.BreakerClosed, breaker.State())
+	assert.Equal(t, 4, calls)
+
+	close(in)
+	require.NoError(t, r.Wait())
+	assert.Equal(t, []string{
+		"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+	}, transitions)
+}
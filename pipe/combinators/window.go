@@ -0,0 +1,364 @@
+package combinators
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// Windows specifies how the items are grouped in time windows. It is created by the
+// TumblingWindows, SlidingWindows or SessionWindows functions.
+type Windows struct {
+	size  time.Duration
+	slide time.Duration
+	gap   time.Duration
+}
+
+// TumblingWindows groups the items in consecutive, non-overlapping windows of the given size.
+// Windows are aligned to the zero time (e.g. windows of 1 minute start at second 0 of each minute).
+func TumblingWindows(size time.Duration) Windows {
+	return Windows{size: size, slide: size}
+}
+
+// SlidingWindows groups the items in overlapping windows of the given size, starting every
+// slide period. An item belongs to size/slide windows.
+// Windows are aligned to the zero time.
+func SlidingWindows(size, slide time.Duration) Windows {
+	return Windows{size: size, slide: slide}
+}
+
+// SessionWindows groups the items of each key in windows that are closed after a gap period
+// without receiving items for the key. Session windows keep all their items in memory until
+// the window is closed, because two sessions can merge if they are bridged by a late item.
+func SessionWindows(gap time.Duration) Windows {
+	return Windows{gap: gap}
+}
+
+// WindowSpec defines the windows, the keys and the aggregation of a Window node.
+type WindowSpec[IN any, K comparable, ACC any] struct {
+	// Windows specifies how the items are grouped in time windows.
+	Windows Windows
+	// Key returns the key of each item. The items of each key are aggregated in their own
+	// windows. If nil, all the items are aggregated in the same windows.
+	Key func(IN) K
+	// Initial value of the aggregation of each window. If the initial value is a pointer,
+	// a map or a slice, the Aggregate function should not modify it, as it would be shared
+	// by all the windows.
+	Initial ACC
+	// Aggregate returns the result of aggregating an item into the accumulated value of a window.
+	Aggregate func(ACC, IN) ACC
+	// EventTime extracts the event time from each item. If nil, the items are grouped by
+	// processing time (the time when the item is received by the node).
+	EventTime func(IN) time.Time
+	// WatermarkDelay is only used with EventTime. It is the maximum time that the items are
+	// expected to arrive out of order: the event time windows are closed when the node
+	// receives an item whose event time is WatermarkDelay later than the end of the window.
+	// The rest of windows are closed when the input of the node is closed.
+	WatermarkDelay time.Duration
+	// AllowedLateness is only used with EventTime. It is the time that a window is kept after
+	// it has been closed, to accept the items that arrive later than the WatermarkDelay. Each
+	// late item causes the window to be forwarded again, marked as Late.
+	AllowedLateness time.Duration
+	// OnLate is invoked, if not nil, with the items that are discarded because they arrive later
+	// than the AllowedLateness.
+	OnLate func(IN)
+}
+
+// WindowResult is the aggregation of the items of a key in a time window.
+type WindowResult[K comparable, ACC any] struct {
+	Key   K
+	Start time.Time
+	End   time.Time
+	// Value is the result of aggregating the items of the window.
+	Value ACC
+	// Count is the number of items in the window.
+	Count int
+	// Late is true if the result updates a window that had been already forwarded,
+	// because it received late items (see WindowSpec.AllowedLateness).
+	Late bool
+}
+
+// Window returns a MiddleFunc that aggregates the input items of each key in time windows, and
+// forwards the result of each window once it is closed. Windows are closed according to the time
+// of the Clock (see WithClock), or to the event time of the items (see WindowSpec.EventTime).
+// The windows that are still open when the input is closed are forwarded before the node ends.
+// The results that are closed at the same time are forwarded sorted by their end and creation time.
+// It returns an error if the durations of the Windows are not positive (e.g. if the Windows
+// have not been created by any of the TumblingWindows, SlidingWindows or SessionWindows
+// functions), so it can be directly returned by a pipe.MiddleProvider.
+func Window[IN any, K comparable, ACC any](spec WindowSpec[IN, K, ACC], opts ...Option) (pipe.MiddleFunc[IN, WindowResult[K, ACC]], error) {
+	if spec.Windows.gap <= 0 && (spec.Windows.size <= 0 || spec.Windows.slide <= 0) {
+		return nil, fmt.Errorf("invalid windows %+v: durations must be positive", spec.Windows)
+	}
+	clock := getOptions(opts...).clock
+	return func(in <-chan IN, out chan<- WindowResult[K, ACC]) {
+		w := &windower[IN, K, ACC]{spec: spec, clock: clock, out: out, open: map[K][]*windowState[IN, ACC]{}}
+		defer w.stopTimer()
+		for {
+			var expired <-chan time.Time
+			if w.timer != nil {
+				expired = w.timer.C()
+			}
+			select {
+			case item, ok := <-in:
+				if !ok {
+					w.closeAll()
+					return
+				}
+				w.add(item)
+			case <-expired:
+				w.timer = nil
+				w.advance(w.clock.Now())
+			}
+		}
+	}, nil
+}
+
+type windowState[IN, ACC any] struct {
+	// seq is the creation order of the window
+	seq   uint64
+	start time.Time
+	end   time.Time
+	acc   ACC
+	// items are only stored for session windows, which are aggregated when they are closed
+	items []IN
+	count int
+	fired bool
+	// updated is true if the window received items after it was fired
+	updated bool
+}
+
+type windower[IN any, K comparable, ACC any] struct {
+	spec  WindowSpec[IN, K, ACC]
+	clock Clock
+	out   chan<- WindowResult[K, ACC]
+	open  map[K][]*windowState[IN, ACC]
+	seq   uint64
+
+	// watermark is only used in event time
+	watermark    time.Time
+	hasWatermark bool
+
+	// timer is only used in processing time, to close the next window
+	timer         Timer
+	timerDeadline time.Time
+}
+
+func (w *windower[IN, K, ACC]) eventTime() bool {
+	return w.spec.EventTime != nil
+}
+
+func (w *windower[IN, K, ACC]) add(item IN) {
+	var ts time.Time
+	if w.eventTime() {
+		ts = w.spec.EventTime(item)
+	} else {
+		ts = w.clock.Now()
+	}
+	var key K
+	if w.spec.Key != nil {
+		key = w.spec.Key(item)
+	}
+	var accepted bool
+	if w.spec.Windows.gap > 0 {
+		accepted = w.addToSession(key, ts, item)
+	} else {
+		accepted = w.addToWindows(key, ts, item)
+	}
+	if !accepted && w.spec.OnLate != nil {
+		w.spec.OnLate(item)
+	}
+	if w.eventTime() {
+		if wm := ts.Add(-w.spec.WatermarkDelay); !w.hasWatermark || wm.After(w.watermark) {
+			w.watermark, w.hasWatermark = wm, true
+		}
+		w.advance(w.watermark)
+	} else {
+		w.advance(ts)
+	}
+}
+
+// tooLate returns whether a window ending at the given time can't accept more items
+func (w *windower[IN, K, ACC]) tooLate(end time.Time) bool {
+	return w.eventTime() && w.hasWatermark && !end.Add(w.spec.AllowedLateness).After(w.watermark)
+}
+
+func (w *windower[IN, K, ACC]) newWindow(start, end time.Time) *windowState[IN, ACC] {
+	w.seq++
+	return &windowState[IN, ACC]{seq: w.seq, start: start, end: end, acc: w.spec.Initial}
+}
+
+// addToWindows adds the item to all the tumbling or sliding windows it belongs to.
+// It returns false if the item is too late for all of them.
+func (w *windower[IN, K, ACC]) addToWindows(key K, ts time.Time, item IN) bool {
+	size, slide := w.spec.Windows.size, w.spec.Windows.slide
+	accepted := false
+	lastStart := ts.Truncate(slide)
+	for start := lastStart; ts.Before(start.Add(size)); start = start.Add(-slide) {
+		end := start.Add(size)
+		if w.tooLate(end) {
+			continue
+		}
+		accepted = true
+		ws := w.find(key, start)
+		if ws == nil {
+			ws = w.newWindow(start, end)
+			w.open[key] = append(w.open[key], ws)
+		}
+		ws.acc = w.spec.Aggregate(ws.acc, item)
+		ws.count++
+		ws.updated = ws.fired
+	}
+	return accepted
+}
+
+func (w *windower[IN, K, ACC]) find(key K, start time.Time) *windowState[IN, ACC] {
+	for _, ws := range w.open[key] {
+		if ws.start.Equal(start) {
+			return ws
+		}
+	}
+	return nil
+}
+
+// addToSession adds the item to the session window of its key, merging all the sessions
+// that are bridged by the item. It returns false if the item is too late.
+func (w *windower[IN, K, ACC]) addToSession(key K, ts time.Time, item IN) bool {
+	session := w.newWindow(ts, ts.Add(w.spec.Windows.gap))
+	session.items = []IN{item}
+	session.count = 1
+	var rest []*windowState[IN, ACC]
+	merged := false
+	for _, ws := range w.open[key] {
+		if ws.start.After(session.end) || session.start.After(ws.end) {
+			rest = append(rest, ws)
+			continue
+		}
+		merged = true
+		if ws.start.Before(session.start) {
+			session.start = ws.start
+		}
+		if ws.end.After(session.end) {
+			session.end = ws.end
+		}
+		if ws.seq < session.seq {
+			session.seq = ws.seq
+		}
+		session.items = append(ws.items, session.items...)
+		session.count += ws.count
+		session.fired = session.fired || ws.fired
+	}
+	if !merged && w.tooLate(session.end) {
+		return false
+	}
+	session.updated = session.fired
+	w.open[key] = append(rest, session)
+	return true
+}
+
+type closedWindow[IN any, K comparable, ACC any] struct {
+	key K
+	ws  *windowState[IN, ACC]
+}
+
+// advance forwards the windows that are closed at the given time, and forgets the windows
+// that can't receive more items
+func (w *windower[IN, K, ACC]) advance(now time.Time) {
+	var closed []closedWindow[IN, K, ACC]
+	for key, windows := range w.open {
+		var keep []*windowState[IN, ACC]
+		for _, ws := range windows {
+			if now.Before(ws.end) {
+				keep = append(keep, ws)
+				continue
+			}
+			if !ws.fired || ws.updated {
+				closed = append(closed, closedWindow[IN, K, ACC]{key: key, ws: ws})
+			}
+			// in event time, closed windows are kept to accept late items
+			if w.eventTime() && !w.tooLate(ws.end) {
+				keep = append(keep, ws)
+			}
+		}
+		if len(keep) == 0 {
+			delete(w.open, key)
+		} else {
+			w.open[key] = keep
+		}
+	}
+	w.forward(closed)
+	if !w.eventTime() {
+		w.schedule()
+	}
+}
+
+// closeAll forwards all the pending windows
+func (w *windower[IN, K, ACC]) closeAll() {
+	var closed []closedWindow[IN, K, ACC]
+	for key, windows := range w.open {
+		for _, ws := range windows {
+			if !ws.fired || ws.updated {
+				closed = append(closed, closedWindow[IN, K, ACC]{key: key, ws: ws})
+			}
+		}
+	}
+	w.open = map[K][]*windowState[IN, ACC]{}
+	w.forward(closed)
+}
+
+func (w *windower[IN, K, ACC]) forward(closed []closedWindow[IN, K, ACC]) {
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].ws.end.Equal(closed[j].ws.end) {
+			return closed[i].ws.end.Before(closed[j].ws.end)
+		}
+		return closed[i].ws.seq < closed[j].ws.seq
+	})
+	for _, c := range closed {
+		ws := c.ws
+		acc := ws.acc
+		if ws.items != nil {
+			acc = w.spec.Initial
+			for _, item := range ws.items {
+				acc = w.spec.Aggregate(acc, item)
+			}
+		}
+		w.out <- WindowResult[K, ACC]{
+			Key:   c.key,
+			Start: ws.start,
+			End:   ws.end,
+			Value: acc,
+			Count: ws.count,
+			Late:  ws.fired,
+		}
+		ws.fired, ws.updated = true, false
+	}
+}
+
+// schedule the timer for the end of the next window, in processing time
+func (w *windower[IN, K, ACC]) schedule() {
+	var next time.Time
+	for _, windows := range w.open {
+		for _, ws := range windows {
+			if next.IsZero() || ws.end.Before(next) {
+				next = ws.end
+			}
+		}
+	}
+	if w.timer != nil && next.Equal(w.timerDeadline) {
+		return
+	}
+	w.stopTimer()
+	if !next.IsZero() {
+		w.timer = w.clock.NewTimer(next.Sub(w.clock.Now()))
+		w.timerDeadline = next
+	}
+}
+
+func (w *windower[IN, K, ACC]) stopTimer() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
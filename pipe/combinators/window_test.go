@@ -0,0 +1,175 @@
+package combinators_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe/combinators"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+type metric struct {
+	name  string
+	value int
+	ts    time.Time
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time {
+	return epoch.Add(time.Duration(seconds) * time.Second)
+}
+
+func sumSpec(windows combinators.Windows) combinators.WindowSpec[metric, string, int] {
+	return combinators.WindowSpec[metric, string, int]{
+		Windows:   windows,
+		Key:       func(m metric) string { return m.name },
+		Aggregate: func(acc int, m metric) int { return acc + m.value },
+	}
+}
+
+type result = combinators.WindowResult[string, int]
+
+// runWindow sends the items to a Window node and returns all the forwarded results
+func runWindow(t *testing.T, spec combinators.WindowSpec[metric, string, int], items ...metric) []result {
+	window, err := combinators.Window(spec)
+	require.NoError(t, err)
+	in, out := make(chan metric), make(chan result, 100)
+	go func() {
+		window(in, out)
+		close(out)
+	}()
+	for _, m := range items {
+		in <- m
+	}
+	close(in)
+	var results []result
+	for r := range out {
+		results = append(results, r)
+	}
+	return results
+}
+
+func TestWindow_TumblingProcessingTime(t *testing.T) {
+	clock := combinators.NewManualClock(at(0))
+	spec := sumSpec(combinators.TumblingWindows(10 * time.Second))
+	// allows waiting for the items to be processed before advancing the clock
+	var processed int32
+	spec.Key = func(m metric) string {
+		atomic.AddInt32(&processed, 1)
+		return m.name
+	}
+	window, err := combinators.Window(spec, combinators.WithClock(clock))
+	require.NoError(t, err)
+	in, out := make(chan metric), make(chan result, 10)
+	go func() {
+		window(in, out)
+		close(out)
+	}()
+	in <- metric{name: "a", value: 1}
+	in <- metric{name: "b", value: 10}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 2 }, timeout, time.Millisecond)
+	clock.Advance(5 * time.Second)
+	in <- metric{name: "a", value: 2}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 3 }, timeout, time.Millisecond)
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, result{Key: "a", Start: at(0), End: at(10), Value: 3, Count: 2},
+		helpers.ReadChannel(t, out, timeout))
+	assert.Equal(t, result{Key: "b", Start: at(0), End: at(10), Value: 10, Count: 1},
+		helpers.ReadChannel(t, out, timeout))
+
+	// pending windows are forwarded when the input is closed
+	in <- metric{name: "a", value: 3}
+	close(in)
+	assert.Equal(t, result{Key: "a", Start: at(10), End: at(20), Value: 3, Count: 1},
+		helpers.ReadChannel(t, out, timeout))
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestWindow_SlidingEventTime(t *testing.T) {
+	spec := sumSpec(combinators.SlidingWindows(10*time.Second, 5*time.Second))
+	spec.EventTime = func(m metric) time.Time { return m.ts }
+	results := runWindow(t, spec,
+		metric{name: "a", value: 1, ts: at(1)},
+		metric{name: "a", value: 2, ts: at(7)},
+		// closes the [-5, 5) window
+		metric{name: "a", value: 4, ts: at(12)},
+		// closes the [0, 10) and [5, 15) windows
+		metric{name: "a", value: 8, ts: at(21)},
+	)
+	assert.Equal(t, []result{
+		{Key: "a", Start: at(-5), End: at(5), Value: 1, Count: 1},
+		{Key: "a", Start: at(0), End: at(10), Value: 3, Count: 2},
+		{Key: "a", Start: at(5), End: at(15), Value: 6, Count: 2},
+		// windows forwarded when the input is closed
+		{Key: "a", Start: at(10), End: at(20), Value: 4, Count: 1},
+		{Key: "a", Start: at(15), End: at(25), Value: 8, Count: 1},
+		{Key: "a", Start: at(20), End: at(30), Value: 8, Count: 1},
+	}, results)
+}
+
+func TestWindow_Lateness(t *testing.T) {
+	var late []metric
+	spec := sumSpec(combinators.TumblingWindows(10 * time.Second))
+	spec.EventTime = func(m metric) time.Time { return m.ts }
+	spec.WatermarkDelay = 2 * time.Second
+	spec.AllowedLateness = 5 * time.Second
+	spec.OnLate = func(m metric) { late = append(late, m) }
+	results := runWindow(t, spec,
+		metric{name: "a", value: 1, ts: at(3)},
+		// out of order, but before the watermark
+		metric{name: "a", value: 2, ts: at(11)},
+		metric{name: "a", value: 4, ts: at(9)},
+		// watermark at 10: closes the [0, 10) window
+		metric{name: "a", value: 8, ts: at(12)},
+		// late item, within the allowed lateness
+		metric{name: "a", value: 16, ts: at(5)},
+		// watermark at 15: the [0, 10) window is discarded
+		metric{name: "a", value: 32, ts: at(17)},
+		metric{name: "a", value: 64, ts: at(8)},
+	)
+	assert.Equal(t, []result{
+		{Key: "a", Start: at(0), End: at(10), Value: 5, Count: 2},
+		{Key: "a", Start: at(0), End: at(10), Value: 21, Count: 3, Late: true},
+		{Key: "a", Start: at(10), End: at(20), Value: 42, Count: 3},
+	}, results)
+	assert.Equal(t, []metric{{name: "a", value: 64, ts: at(8)}}, late)
+}
+
+func TestWindow_Session(t *testing.T) {
+	spec := sumSpec(combinators.SessionWindows(5 * time.Second))
+	spec.EventTime = func(m metric) time.Time { return m.ts }
+	spec.WatermarkDelay = 10 * time.Second
+	results := runWindow(t, spec,
+		metric{name: "a", value: 1, ts: at(0)},
+		metric{name: "b", value: 100, ts: at(1)},
+		metric{name: "a", value: 2, ts: at(3)},
+		metric{name: "a", value: 4, ts: at(12)},
+		// bridges the [0, 8) and [12, 17) sessions
+		metric{name: "a", value: 8, ts: at(7)},
+		metric{name: "a", value: 16, ts: at(30)},
+	)
+	assert.Equal(t, []result{
+		{Key: "b", Start: at(1), End: at(6), Value: 100, Count: 1},
+		{Key: "a", Start: at(0), End: at(17), Value: 15, Count: 4},
+		{Key: "a", Start: at(30), End: at(35), Value: 16, Count: 1},
+	}, results)
+}
+
+func TestWindow_InvalidWindows(t *testing.T) {
+	for _, windows := range []combinators.Windows{
+		{},
+		combinators.TumblingWindows(0),
+		combinators.SlidingWindows(time.Minute, -time.Second),
+		combinators.SessionWindows(-time.Second),
+	} {
+		_, err := combinators.Window(sumSpec(windows))
+		assert.Error(t, err)
+	}
+}
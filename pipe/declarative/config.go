@@ -0,0 +1,254 @@
+package declarative
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configValue is a JSON value of a node configuration, along with the position of the
+// document where it was set. Objects are split into their fields, so the fields of the
+// same configuration can be set by different documents (see Merge).
+type configValue struct {
+	pos position
+	// raw JSON of the non-object values
+	raw json.RawMessage
+	// keys of the object values, in order, and their values
+	keys   []string
+	fields map[string]*configValue
+}
+
+func (c *configValue) isObject() bool {
+	return c.fields != nil
+}
+
+// config parses the next value of the document as a configuration value
+func (p *parser) config(pos position) (*configValue, error) {
+	if pos.offset >= int64(len(p.src.data)) || p.src.data[pos.offset] != '{' {
+		cv := &configValue{pos: pos}
+		return cv, p.value(pos, &cv.raw)
+	}
+	cv := &configValue{pos: pos, fields: map[string]*configValue{}}
+	err := p.object(func(key string, pos position) error {
+		field, err := p.config(pos)
+		if err != nil {
+			return err
+		}
+		if _, ok := cv.fields[key]; !ok {
+			cv.keys = append(cv.keys, key)
+		}
+		cv.fields[key] = field
+		return nil
+	})
+	return cv, err
+}
+
+// merge returns a copy of the base value, overridden by the overlay value. Objects are merged
+// field by field, and any other value is replaced.
+func (c *configValue) merge(overlay *configValue) *configValue {
+	if c == nil || !c.isObject() || !overlay.isObject() {
+		return overlay
+	}
+	merged := &configValue{pos: c.pos, keys: append([]string{}, c.keys...), fields: map[string]*configValue{}}
+	for k, v := range c.fields {
+		merged.fields[k] = v
+	}
+	for _, k := range overlay.keys {
+		if base, ok := merged.fields[k]; ok {
+			merged.fields[k] = base.merge(overlay.fields[k])
+		} else {
+			merged.keys = append(merged.keys, k)
+			merged.fields[k] = overlay.fields[k]
+		}
+	}
+	return merged
+}
+
+// lookup returns the value in the given dot-separated path of fields
+func (c *configValue) lookup(path string) (*configValue, bool) {
+	if path == "" {
+		return c, true
+	}
+	key, rest, _ := strings.Cut(path, ".")
+	if !c.isObject() {
+		return nil, false
+	}
+	field, ok := c.fields[key]
+	if !ok {
+		return nil, false
+	}
+	return field.lookup(rest)
+}
+
+// segment of the encoded configuration that was set in the given position
+type segment struct {
+	start, end int64
+	pos        position
+}
+
+// encode the configuration value into JSON, recording the segments of each value
+func (c *configValue) encode(buf *bytes.Buffer, segments *[]segment) {
+	start := int64(buf.Len())
+	if !c.isObject() {
+		buf.Write(c.raw)
+	} else {
+		buf.WriteByte('{')
+		for i, k := range c.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(k)
+			buf.Write(key)
+			buf.WriteByte(':')
+			c.fields[k].encode(buf, segments)
+		}
+		buf.WriteByte('}')
+	}
+	*segments = append(*segments, segment{start: start, end: int64(buf.Len()), pos: c.pos})
+}
+
+// positionOf returns the position of the innermost value that contains the offset of the
+// encoded configuration
+func positionOf(segments []segment, offset int64) position {
+	var found *segment
+	for i := range segments {
+		s := &segments[i]
+		if s.start < offset && offset <= s.end && (found == nil || s.end-s.start < found.end-found.start) {
+			found = s
+		}
+	}
+	if found == nil {
+		return position{}
+	}
+	return found.pos
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// checkFields returns an error pointing to the first field of the configuration of the
+// node that does not belong to the given type
+func (c *configValue) checkFields(node string, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !c.isObject() || reflect.PointerTo(t).Implements(jsonUnmarshaler) ||
+		reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t, map[string]jsonField{})
+		for _, k := range c.keys {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				return c.fields[k].pos.errorf("configuration of node %q: unknown field %q. Valid fields: %s",
+					node, k, validFields(fields))
+			}
+			if err := c.fields[k].checkFields(node, f.typ); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range c.keys {
+			if err := c.fields[k].checkFields(node, t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns, by their lowercase name, the fields that can be decoded by
+// the encoding/json package into the given struct type
+func jsonFields(t reflect.Type, fields map[string]jsonField) map[string]jsonField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// fields of embedded structs are promoted, unless they are shadowed
+				for k, v := range jsonFields(ft, map[string]jsonField{}) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = jsonField{name: name, typ: f.Type}
+	}
+	return fields
+}
+
+func validFields(fields map[string]jsonField) string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// decodeConfig decodes the configuration of the node into the CFG type. Unknown fields are
+// reported as errors.
+func decodeConfig[CFG any](node *Node) (CFG, error) {
+	var cfg CFG
+	data, segments := node.Config, []segment{{start: 0, end: int64(len(node.Config)), pos: node.configPos}}
+	if node.config != nil {
+		if err := node.config.checkFields(node.Name, typeOf[CFG]()); err != nil {
+			return cfg, err
+		}
+		buf := bytes.Buffer{}
+		segments = segments[:0]
+		node.config.encode(&buf, &segments)
+		data = buf.Bytes()
+	}
+	if len(data) == 0 {
+		return cfg, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		var terr *json.UnmarshalTypeError
+		if errors.As(err, &terr) {
+			pos := positionOf(segments, terr.Offset)
+			if node.config == nil && pos.src != nil {
+				// the offset is relative to the configuration
+				pos.offset += terr.Offset
+			}
+			if terr.Field == "" {
+				return cfg, pos.errorf("configuration of node %q: expected %s, found %s", node.Name, terr.Type, terr.Value)
+			}
+			return cfg, pos.errorf("configuration of node %q: field %q expects %s, found %s",
+				node.Name, terr.Field, terr.Type, terr.Value)
+		}
+		return cfg, node.configPos.errorf("configuration of node %q: %w", node.Name, err)
+	}
+	return cfg, nil
+}
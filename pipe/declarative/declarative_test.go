@@ -0,0 +1,187 @@
+package declarative_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+	"github.com/mariomac/pipes/pipe/declarative"
+)
+
+type counterConfig struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type multiplierConfig struct {
+	Factor int `json:"factor"`
+}
+
+// testRegistry returns a Registry whose collector nodes append the received items to the results
+func testRegistry(t *testing.T, results *[]string) *declarative.Registry {
+	r := declarative.NewRegistry()
+	require.NoError(t, declarative.RegisterStart(r, "counter", func(cfg counterConfig) (pipe.StartFunc[int], error) {
+		return func(out chan<- int) {
+			for i := cfg.From; i <= cfg.To; i++ {
+				out <- i
+			}
+		}, nil
+	}))
+	require.NoError(t, declarative.RegisterMiddle(r, "multiplier", func(cfg multiplierConfig) (pipe.MiddleFunc[int, int], error) {
+		if cfg.Factor == 0 {
+			return nil, errors.New("factor can't be zero")
+		}
+		return combinators.Map(func(i int) int { return i * cfg.Factor }), nil
+	}))
+	require.NoError(t, declarative.RegisterMiddle(r, "toString", func(struct{}) (pipe.MiddleFunc[int, string], error) {
+		return combinators.Map(func(i int) string { return strings.Repeat("*", i) }), nil
+	}))
+	require.NoError(t, declarative.RegisterFinal(r, "collector", func(struct{}) (pipe.FinalFunc[string], error) {
+		return combinators.ForEach(func(s string) { *results = append(*results, s) }), nil
+	}))
+	return r
+}
+
+func TestBuild(t *testing.T) {
+	def, err := declarative.Parse("pipeline.json", []byte(`{
+  "nodes": [
+    {"name": "count", "type": "counter", "config": {"from": 1, "to": 3}},
+    {"name": "double", "type": "multiplier", "config": {"factor": 2}, "bufferLength": 10},
+    {"name": "stars", "type": "toString"},
+    {"name": "collect", "type": "collector"}
+  ],
+  "edges": [
+    {"from": "count", "to": "double"},
+    {"from": "double", "to": "stars"},
+    {"from": "stars", "to": "collect"}
+  ]
+}`))
+	require.NoError(t, err)
+	var results []string
+	runner, err := testRegistry(t, &results).Build(def)
+	require.NoError(t, err)
+
+	var names []string
+	for _, n := range runner.Graph().Nodes {
+		names = append(names, n.Name)
+	}
+	assert.ElementsMatch(t, []string{"count", "double", "stars", "collect"}, names)
+
+	runner.Start()
+	require.NoError(t, runner.Wait())
+	assert.Equal(t, []string{"**", "****", "******"}, results)
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		document string
+		err      string
+	}{{
+		name: "syntax error",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter",}
+]}`,
+		err: "pipeline.json:2:39: invalid character",
+	}, {
+		name: "unknown field",
+		document: `{"nodes": [
+  {"name": "count", "kind": "counter"}
+]}`,
+		err: `pipeline.json:2:29: unknown field "kind"`,
+	}, {
+		name: "unknown type",
+		document: `{"nodes": [
+  {"name": "count", "type": "counting"}
+]}`,
+		err: `pipeline.json:2:29: unknown type "counting" of node "count"`,
+	}, {
+		name: "duplicate node",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter"},
+  {"name": "count", "type": "counter"}
+]}`,
+		err: `pipeline.json:3:3: duplicate node name: "count"`,
+	}, {
+		name: "invalid configuration",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter", "config": {
+    "from": 1,
+    "to": "ten"
+  }}
+]}`,
+		err: `pipeline.json:4:11: configuration of node "count": field "to" expects int, found string`,
+	}, {
+		name: "undefined node",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter"}
+], "edges": [
+  {"from": "count", "to": "collect"}
+]}`,
+		err: `pipeline.json:4:3: undefined destination node "collect"`,
+	}, {
+		name: "incompatible types",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter"},
+  {"name": "collect", "type": "collector"}
+], "edges": [
+  {"from": "count", "to": "collect"}
+]}`,
+		err: `pipeline.json:5:3: can't connect node "count" (output type int) to node "collect" (input type string)`,
+	}, {
+		name: "sending from final node",
+		document: `{"nodes": [
+  {"name": "count", "type": "counter"},
+  {"name": "stars", "type": "toString"},
+  {"name": "collect", "type": "collector"}
+], "edges": [
+  {"from": "count", "to": "stars"},
+  {"from": "stars", "to": "collect"},
+  {"from": "collect", "to": "stars"}
+]}`,
+		err: `pipeline.json:8:3: Final node "collect" can't send data to other nodes`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var results []string
+			def, err := declarative.Parse("pipeline.json", []byte(tc.document))
+			if err == nil {
+				_, err = testRegistry(t, &results).Build(def)
+			}
+			require.Error(t, err)
+			var derr *declarative.Error
+			require.ErrorAs(t, err, &derr)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestBuild_PipelineErrors(t *testing.T) {
+	var results []string
+	r := testRegistry(t, &results)
+
+	// errors from the providers
+	def, err := declarative.Parse("pipeline.json", []byte(`{"nodes": [
+  {"name": "count", "type": "counter"},
+  {"name": "mult", "type": "multiplier"}
+], "edges": [{"from": "count", "to": "mult"}]}`))
+	require.NoError(t, err)
+	_, err = r.Build(def)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "factor can't be zero")
+
+	// validation errors of the pipe.Builder
+	def, err = declarative.Parse("pipeline.json", []byte(`{"nodes": [
+  {"name": "count", "type": "counter"},
+  {"name": "stars", "type": "toString"}
+], "edges": [{"from": "count", "to": "stars"}]}`))
+	require.NoError(t, err)
+	_, err = r.Build(def)
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"stars"}, verr.Dangling)
+}
@@ -0,0 +1,370 @@
+package declarative
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Definition of a pipeline: the node instances and the edges between them.
+type Definition struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Node is an instance of a node type that has been registered in a Registry.
+type Node struct {
+	// Name of the node instance. It must be unique in the pipeline.
+	Name string
+	// Type of the node, as it was registered in the Registry.
+	Type string
+	// Config is the JSON configuration of the node, which is decoded into the configuration
+	// type of the registered node type. If empty, the zero value of the configuration is used.
+	// Definitions returned by Parse and Merge also keep track of the document that set each
+	// configuration field, so their Config should not be modified.
+	Config json.RawMessage
+	// BufferLength of the input channel of the node (see pipe.ChannelBufferLen).
+	BufferLength int
+	// Enabled, if false, makes the Start and Final nodes to be ignored, and the Middle nodes
+	// to be bypassed. If nil, the node is enabled.
+	Enabled *bool
+
+	pos             position
+	typePos         position
+	configPos       position
+	bufferLengthPos position
+	enabledPos      position
+	// config is the parsed Config, along with the positions of its fields
+	config *configValue
+}
+
+func (n *Node) enabled() bool {
+	return n.Enabled == nil || *n.Enabled
+}
+
+// Edge connects the output of the From node to the input of the To node.
+type Edge struct {
+	From string
+	To   string
+
+	pos position
+}
+
+// source document of a Definition
+type source struct {
+	name string
+	// data of the document, after the environment variables have been expanded
+	data []byte
+	// original data of the document, and the expansions of the environment variables
+	original   []byte
+	expansions []expansion
+}
+
+// originalOffset converts an offset of the expanded data into an offset of the original data
+func (s *source) originalOffset(offset int64) int64 {
+	delta := int64(0)
+	for _, e := range s.expansions {
+		if offset < e.start {
+			break
+		}
+		if offset < e.end {
+			return e.originalStart
+		}
+		delta = e.originalEnd - e.end
+	}
+	return offset + delta
+}
+
+// position of a JSON value in its source document
+type position struct {
+	src    *source
+	offset int64
+}
+
+func (p position) isSet() bool {
+	return p.src != nil
+}
+
+// lineColumn returns the line and column of the position in the original document
+func (p position) lineColumn() (line, column int) {
+	line, column = 1, 1
+	for _, c := range p.src.original[:p.src.originalOffset(p.offset)] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func (p position) String() string {
+	if !p.isSet() {
+		return ""
+	}
+	line, column := p.lineColumn()
+	return fmt.Sprintf("%s:%d:%d", p.src.name, line, column)
+}
+
+// errorf returns an *Error pointing to the position
+func (p position) errorf(format string, args ...any) error {
+	return p.wrap(fmt.Errorf(format, args...))
+}
+
+func (p position) wrap(err error) error {
+	e := &Error{Err: err}
+	if !p.isSet() {
+		// the value has not been parsed from a document
+		return e
+	}
+	e.Source = p.src.name
+	e.Line, e.Column = p.lineColumn()
+	return e
+}
+
+// Error in a pipeline definition. If the offending value has been parsed from a document,
+// it points to its line and column. When multiple documents are merged, it points to the
+// document that set the value.
+type Error struct {
+	// Source is the name of the document, as it was passed to the Parse function.
+	Source string
+	// Line and Column of the offending value, starting at 1. They are 0 if the value
+	// has not been parsed from a document.
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Source, e.Line, e.Column, e.Err.Error())
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Parse a pipeline Definition from a JSON document with the following structure:
+//
+//	{
+//	  "nodes": [
+//	    {"name": "reader", "type": "fileReader", "config": {"path": "${LOG_FILE}"}},
+//	    {"name": "grep", "type": "grep", "config": {"pattern": "ERROR"}, "bufferLength": 10},
+//	    {"name": "printer", "type": "stdout", "enabled": false}
+//	  ],
+//	  "edges": [
+//	    {"from": "reader", "to": "grep"},
+//	    {"from": "grep", "to": "printer"}
+//	  ]
+//	}
+//
+// Only the name of the nodes is mandatory, so the document can be an overlay that only
+// overrides some values of another Definition (see Merge).
+//
+// Any ${NAME} and ${NAME:-default} expression is replaced by the value of the NAME environment
+// variable, or by the default value if the variable is not defined. Undefined variables without
+// default value are reported as errors. Values inside JSON strings are escaped, while values
+// outside strings are inserted verbatim, so they can also define numbers or booleans.
+// $${ is replaced by a literal ${.
+//
+// The name of the document (e.g. its file name) is used to report the errors. Any error is
+// returned as an *Error that points to the offending line of the document.
+func Parse(name string, data []byte) (*Definition, error) {
+	src, err := expandEnv(name, data)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{src: src, dec: json.NewDecoder(bytes.NewReader(src.data))}
+	def := &Definition{}
+	err = p.object(func(key string, pos position) error {
+		switch key {
+		case "nodes":
+			return p.array(func(pos position) error {
+				node, err := p.node(pos)
+				if err == nil && def.node(node.Name) != nil {
+					err = pos.errorf("%w: %q", ErrDuplicateNode, node.Name)
+				}
+				def.Nodes = append(def.Nodes, node)
+				return err
+			})
+		case "edges":
+			return p.array(func(pos position) error {
+				edge, err := p.edge(pos)
+				def.Edges = append(def.Edges, edge)
+				return err
+			})
+		default:
+			return pos.errorf("unknown field %q", key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.pos().errorf("unexpected data after the pipeline definition")
+	}
+	return def, nil
+}
+
+// parser walks a JSON document keeping track of the position of its values
+type parser struct {
+	src *source
+	dec *json.Decoder
+}
+
+// pos returns the position of the next value, skipping the whitespaces and separators
+// that haven't been consumed by the decoder yet
+func (p *parser) pos() position {
+	offset := p.dec.InputOffset()
+	for ; offset < int64(len(p.src.data)); offset++ {
+		switch p.src.data[offset] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+		default:
+			return position{src: p.src, offset: offset}
+		}
+	}
+	return position{src: p.src, offset: offset}
+}
+
+// syntaxError converts the errors from the decoder into an *Error
+func (p *parser) syntaxError(pos position, err error) error {
+	var serr *json.SyntaxError
+	if errors.As(err, &serr) {
+		return position{src: p.src, offset: serr.Offset}.wrap(err)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return pos.wrap(err)
+}
+
+func (p *parser) delim(expected json.Delim) error {
+	pos := p.pos()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return p.syntaxError(pos, err)
+	}
+	if tok != expected {
+		return pos.errorf("expected %q, found %v", expected, tok)
+	}
+	return nil
+}
+
+// object walks a JSON object, invoking the field function for each key. The field function
+// must consume the value of the key.
+func (p *parser) object(field func(key string, pos position) error) error {
+	if err := p.delim('{'); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		pos := p.pos()
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.syntaxError(pos, err)
+		}
+		if err := field(tok.(string), p.pos()); err != nil {
+			return err
+		}
+	}
+	return p.delim('}')
+}
+
+// array walks a JSON array, invoking the element function for each element. The element
+// function must consume the element.
+func (p *parser) array(element func(pos position) error) error {
+	if err := p.delim('['); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		if err := element(p.pos()); err != nil {
+			return err
+		}
+	}
+	return p.delim(']')
+}
+
+// value decodes the next value into dst
+func (p *parser) value(pos position, dst any) error {
+	if err := p.dec.Decode(dst); err != nil {
+		var terr *json.UnmarshalTypeError
+		if errors.As(err, &terr) {
+			return pos.errorf("expected %s, found %s", terr.Type, terr.Value)
+		}
+		return p.syntaxError(pos, err)
+	}
+	return nil
+}
+
+func (p *parser) node(pos position) (Node, error) {
+	node := Node{pos: pos}
+	err := p.object(func(key string, pos position) error {
+		switch key {
+		case "name":
+			return p.value(pos, &node.Name)
+		case "type":
+			node.typePos = pos
+			return p.value(pos, &node.Type)
+		case "config":
+			node.configPos = pos
+			cfg, err := p.config(pos)
+			if err != nil {
+				return err
+			}
+			node.config = cfg
+			node.Config = cfg.raw
+			if cfg.isObject() {
+				buf := bytes.Buffer{}
+				cfg.encode(&buf, &[]segment{})
+				node.Config = buf.Bytes()
+			}
+			return nil
+		case "bufferLength":
+			node.bufferLengthPos = pos
+			if err := p.value(pos, &node.BufferLength); err != nil {
+				return err
+			}
+			if node.BufferLength < 0 {
+				return pos.errorf("bufferLength can't be negative")
+			}
+			return nil
+		case "enabled":
+			node.enabledPos = pos
+			return p.value(pos, &node.Enabled)
+		default:
+			return pos.errorf("unknown field %q", key)
+		}
+	})
+	if err != nil {
+		return node, err
+	}
+	if node.Name == "" {
+		return node, pos.errorf("missing node name")
+	}
+	return node, nil
+}
+
+func (p *parser) edge(pos position) (Edge, error) {
+	edge := Edge{pos: pos}
+	err := p.object(func(key string, pos position) error {
+		switch key {
+		case "from":
+			return p.value(pos, &edge.From)
+		case "to":
+			return p.value(pos, &edge.To)
+		default:
+			return pos.errorf("unknown field %q", key)
+		}
+	})
+	if err != nil {
+		return edge, err
+	}
+	if edge.From == "" || edge.To == "" {
+		return edge, pos.errorf("edges require both \"from\" and \"to\" nodes")
+	}
+	return edge, nil
+}
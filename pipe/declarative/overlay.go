@@ -0,0 +1,239 @@
+package declarative
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Merge returns a new Definition that results from applying, in order, the overlays on top
+// of the base Definition. The nodes are matched by name:
+//   - The nodes that are not defined in the base Definition are appended.
+//   - The Type, BufferLength and Enabled values that are set by an overlay replace the
+//     values of the base Definition.
+//   - The configurations are merged field by field, recursively. Any value that is not a JSON
+//     object replaces the previous value.
+//
+// The edges of the overlays are appended to the edges of the base Definition, unless they
+// already exist.
+// The errors of the merged Definition point to the document that set the offending value,
+// and the Origin method tells which document set each value.
+func Merge(base *Definition, overlays ...*Definition) *Definition {
+	merged := &Definition{
+		Nodes: append([]Node{}, base.Nodes...),
+		Edges: append([]Edge{}, base.Edges...),
+	}
+	for _, overlay := range overlays {
+		for _, node := range overlay.Nodes {
+			if existing := merged.node(node.Name); existing != nil {
+				existing.override(&node)
+			} else {
+				merged.Nodes = append(merged.Nodes, node)
+			}
+		}
+		for _, edge := range overlay.Edges {
+			if !merged.hasEdge(edge.From, edge.To) {
+				merged.Edges = append(merged.Edges, edge)
+			}
+		}
+	}
+	return merged
+}
+
+func (d *Definition) node(name string) *Node {
+	for i := range d.Nodes {
+		if d.Nodes[i].Name == name {
+			return &d.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (d *Definition) hasEdge(from, to string) bool {
+	for _, e := range d.Edges {
+		if e.From == from && e.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// override the values of the node with the values that are set in the overlay
+func (n *Node) override(overlay *Node) {
+	if overlay.Type != "" || overlay.typePos.isSet() {
+		n.Type, n.typePos = overlay.Type, overlay.typePos
+	}
+	if overlay.BufferLength != 0 || overlay.bufferLengthPos.isSet() {
+		n.BufferLength, n.bufferLengthPos = overlay.BufferLength, overlay.bufferLengthPos
+	}
+	if overlay.Enabled != nil {
+		n.Enabled, n.enabledPos = overlay.Enabled, overlay.enabledPos
+	}
+	if len(overlay.Config) == 0 {
+		return
+	}
+	if n.config == nil || overlay.config == nil {
+		// configurations that haven't been parsed from a document can't be merged
+		n.Config, n.configPos, n.config = overlay.Config, overlay.configPos, overlay.config
+		return
+	}
+	n.config = n.config.merge(overlay.config)
+	n.configPos = n.config.pos
+	buf := bytes.Buffer{}
+	n.config.encode(&buf, &[]segment{})
+	n.Config = buf.Bytes()
+}
+
+// Changed returns the names of the nodes of the next Definition whose type, configuration,
+// buffer length or enabled status differ from the node with the same name in the previous
+// Definition. They can be passed to pipe.Runner.Reconfigure, so the running nodes are replaced
+// by nodes with the new configuration, instead of being kept.
+func Changed(previous, next *Definition) []string {
+	var changed []string
+	for i := range next.Nodes {
+		n := &next.Nodes[i]
+		p := previous.node(n.Name)
+		if p == nil {
+			continue
+		}
+		if p.Type != n.Type || p.BufferLength != n.BufferLength || p.enabled() != n.enabled() ||
+			!sameConfig(p.Config, n.Config) {
+			changed = append(changed, n.Name)
+		}
+	}
+	return changed
+}
+
+// sameConfig returns true if both configurations are empty or contain the same JSON value
+func sameConfig(a, b json.RawMessage) bool {
+	var va, vb any
+	if len(a) > 0 && json.Unmarshal(a, &va) != nil {
+		return false
+	}
+	if len(b) > 0 && json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// Origin returns the position of the document (e.g. "overlays/prod.json:12:5") that set the
+// given field of a node: "type", "bufferLength", "enabled", "config", or a path of configuration
+// fields prefixed by "config." (e.g. "config.sink.url"). The "name" field returns the position
+// where the node was first defined.
+// It returns an empty string if the node or the field has not been set by any document.
+func (d *Definition) Origin(node, field string) string {
+	n := d.node(node)
+	if n == nil {
+		return ""
+	}
+	switch field {
+	case "name":
+		return n.pos.String()
+	case "type":
+		return n.typePos.String()
+	case "bufferLength":
+		return n.bufferLengthPos.String()
+	case "enabled":
+		return n.enabledPos.String()
+	case "config":
+		return n.configPos.String()
+	}
+	path := strings.TrimPrefix(field, "config.")
+	if path == field || n.config == nil {
+		return ""
+	}
+	if cv, ok := n.config.lookup(path); ok {
+		return cv.pos.String()
+	}
+	return ""
+}
+
+// Load parses the files with the base pipeline Definition and its overlays, and returns
+// the result of merging them in order (see Parse and Merge).
+func Load(base string, overlays ...string) (*Definition, error) {
+	var defs []*Definition
+	for _, file := range append([]string{base}, overlays...) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("loading pipeline definition: %w", err)
+		}
+		def, err := Parse(file, data)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return Merge(defs[0], defs[1:]...), nil
+}
+
+// expansion of an environment variable. The start and end offsets are relative to the
+// expanded data
+type expansion struct {
+	start, end                 int64
+	originalStart, originalEnd int64
+}
+
+// expandEnv replaces the ${NAME} and ${NAME:-default} expressions of the document by the value of
+// the environment variables
+func expandEnv(name string, data []byte) (*source, error) {
+	src := &source{name: name, original: data}
+	if !bytes.Contains(data, []byte("${")) {
+		src.data = data
+		return src, nil
+	}
+	expanded := bytes.Buffer{}
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString && c == '\\' && i+1 < len(data):
+			expanded.WriteByte(c)
+			i++
+			expanded.WriteByte(data[i])
+			continue
+		case c == '"':
+			inString = !inString
+		case c == '$' && bytes.HasPrefix(data[i:], []byte("$${")):
+			src.addExpansion(&expanded, "${", i, i+3)
+			i += 2
+			continue
+		case c == '$' && bytes.HasPrefix(data[i:], []byte("${")):
+			end := bytes.IndexByte(data[i:], '}')
+			if end < 0 {
+				return nil, position{src: src, offset: int64(expanded.Len())}.errorf("unterminated environment variable expression")
+			}
+			end += i + 1
+			variable, def, hasDefault := strings.Cut(string(data[i+2:end-1]), ":-")
+			value, ok := os.LookupEnv(variable)
+			if !ok {
+				if !hasDefault {
+					return nil, position{src: src, offset: int64(expanded.Len())}.errorf("undefined environment variable %q", variable)
+				}
+				value = def
+			}
+			if inString {
+				// the value is quoted and escaped, and then the quotes are removed
+				quoted, _ := json.Marshal(value)
+				value = string(quoted[1 : len(quoted)-1])
+			}
+			src.addExpansion(&expanded, value, i, end)
+			i = end - 1
+			continue
+		}
+		expanded.WriteByte(c)
+	}
+	src.data = expanded.Bytes()
+	return src, nil
+}
+
+// addExpansion writes the value of the expansion of the original data between
+// the start and end offsets
+func (s *source) addExpansion(expanded *bytes.Buffer, value string, start, end int) {
+	e := expansion{start: int64(expanded.Len()), originalStart: int64(start), originalEnd: int64(end)}
+	expanded.WriteString(value)
+	e.end = int64(expanded.Len())
+	s.expansions = append(s.expansions, e)
+}
@@ -0,0 +1,225 @@
+package declarative_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+	"github.com/mariomac/pipes/pipe/declarative"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+const timeout = 2 * time.Second
+
+type sinkConfig struct {
+	Prefix string `json:"prefix"`
+	Target struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"target"`
+}
+
+const basePipeline = `{
+  "nodes": [
+    {"name": "count", "type": "counter", "config": {"from": 1, "to": 3}},
+    {"name": "double", "type": "multiplier", "config": {"factor": 2}},
+    {"name": "sink", "type": "sink", "config": {
+      "prefix": "${PREFIX:-dev}",
+      "target": {"host": "localhost", "port": 8080}
+    }}
+  ],
+  "edges": [
+    {"from": "count", "to": "double"},
+    {"from": "double", "to": "sink"}
+  ]
+}`
+
+const prodOverlay = `{
+  "nodes": [
+    {"name": "double", "enabled": false},
+    {"name": "sink", "bufferLength": 100, "config": {
+      "target": {"host": "${SINK_HOST}"}
+    }}
+  ]
+}`
+
+// writeFiles writes the documents in a temporary folder, and returns their paths
+func writeFiles(t *testing.T, docs ...string) []string {
+	dir := t.TempDir()
+	var files []string
+	for i, doc := range docs {
+		file := filepath.Join(dir, []string{"base.json", "prod.json", "extra.json"}[i])
+		require.NoError(t, os.WriteFile(file, []byte(doc), 0o600))
+		files = append(files, file)
+	}
+	return files
+}
+
+// sinkRegistry returns a testRegistry with a sink node type that forwards its configuration
+// and the received items to the results
+func sinkRegistry(t *testing.T, results *[]string, configs *[]sinkConfig) *declarative.Registry {
+	r := testRegistry(t, results)
+	require.NoError(t, declarative.RegisterFinal(r, "sink", func(cfg sinkConfig) (pipe.FinalFunc[int], error) {
+		*configs = append(*configs, cfg)
+		return combinators.ForEach(func(i int) {
+			*results = append(*results, cfg.Prefix+":"+cfg.Target.Host+":"+strconv.Itoa(i))
+		}), nil
+	}))
+	return r
+}
+
+func TestLoad_Overlays(t *testing.T) {
+	t.Setenv("SINK_HOST", "prod.example.com")
+	files := writeFiles(t, basePipeline, prodOverlay)
+
+	def, err := declarative.Load(files[0], files[1])
+	require.NoError(t, err)
+
+	var results []string
+	var configs []sinkConfig
+	runner, err := sinkRegistry(t, &results, &configs).Build(def)
+	require.NoError(t, err)
+	runner.Start()
+	require.NoError(t, runner.Wait())
+
+	// the multiplier node is bypassed
+	assert.Equal(t, []string{"dev:prod.example.com:1", "dev:prod.example.com:2", "dev:prod.example.com:3"}, results)
+	require.Len(t, configs, 1)
+	assert.Equal(t, 8080, configs[0].Target.Port)
+
+	assert.Equal(t, files[0]+":5:5", def.Origin("sink", "name"))
+	assert.Equal(t, files[1]+":4:38", def.Origin("sink", "bufferLength"))
+	assert.Equal(t, files[1]+":3:35", def.Origin("double", "enabled"))
+	assert.Equal(t, files[0]+":6:17", def.Origin("sink", "config.prefix"))
+	assert.Equal(t, files[1]+":5:26", def.Origin("sink", "config.target.host"))
+	assert.Equal(t, files[0]+":7:47", def.Origin("sink", "config.target.port"))
+	assert.Empty(t, def.Origin("double", "bufferLength"))
+	assert.Empty(t, def.Origin("sink", "config.target.path"))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Setenv("SINK_HOST", "prod.example.com")
+	for _, tc := range []struct {
+		name    string
+		overlay string
+		err     string
+	}{{
+		name: "unknown configuration field",
+		overlay: `{"nodes": [
+  {"name": "sink", "config": {"target": {"hots": "prod"}}}
+]}`,
+		err: `extra.json:2:50: configuration of node "sink": unknown field "hots". Valid fields: host, port`,
+	}, {
+		name: "invalid configuration field",
+		overlay: `{"nodes": [
+  {"name": "sink", "config": {"target": {"port": "${SINK_HOST}"}}}
+]}`,
+		err: `extra.json:2:50: configuration of node "sink": field "target.port" expects int, found string`,
+	}, {
+		name: "undefined environment variable",
+		overlay: `{"nodes": [
+  {"name": "sink", "config": {"target": {"host": "${UNDEFINED_HOST}"}}}
+]}`,
+		err: `extra.json:2:51: undefined environment variable "UNDEFINED_HOST"`,
+	}, {
+		name: "disabled node with different input and output types",
+		overlay: `{"nodes": [
+  {"name": "stars", "type": "toString", "enabled": false}
+]}`,
+		err: `extra.json:2:52: Middle node "stars" can't be disabled`,
+	}, {
+		name: "new node without type",
+		overlay: `{"nodes": [
+  {"name": "printer"}
+]}`,
+		err: `extra.json:2:3: missing type of node "printer"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			files := writeFiles(t, basePipeline, prodOverlay, tc.overlay)
+			def, err := declarative.Load(files[0], files[1:]...)
+			if err == nil {
+				var results []string
+				var configs []sinkConfig
+				_, err = sinkRegistry(t, &results, &configs).Build(def)
+			}
+			require.Error(t, err)
+			var derr *declarative.Error
+			require.ErrorAs(t, err, &derr)
+			assert.Equal(t, files[2], derr.Source)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestParse_EnvExpansion(t *testing.T) {
+	t.Setenv("FROM", "2")
+	t.Setenv("NAME", `"quoted"`)
+	def, err := declarative.Parse("pipeline.json", []byte(`{"nodes": [
+  {"name": "count", "type": "counter", "config": {"from": ${FROM}, "to": ${TO:-5}, "name": "${NAME}", "lit": "$${FROM}"}}
+]}`))
+	require.NoError(t, err)
+	require.Len(t, def.Nodes, 1)
+	assert.JSONEq(t, `{"from": 2, "to": 5, "name": "\"quoted\"", "lit": "${FROM}"}`, string(def.Nodes[0].Config))
+	// positions refer to the original document
+	assert.Equal(t, "pipeline.json:2:74", def.Origin("count", "config.to"))
+	assert.Equal(t, "pipeline.json:2:110", def.Origin("count", "config.lit"))
+}
+
+func TestChanged_Reconfigure(t *testing.T) {
+	input, output := make(chan int), make(chan string, 10)
+	var results []string
+	r := testRegistry(t, &results)
+	require.NoError(t, declarative.RegisterStart(r, "feed", func(struct{}) (pipe.StartFunc[int], error) {
+		return func(out chan<- int) {
+			for i := range input {
+				out <- i
+			}
+		}, nil
+	}))
+	require.NoError(t, declarative.RegisterFinal(r, "notifier", func(struct{}) (pipe.FinalFunc[string], error) {
+		return combinators.ForEach(func(s string) { output <- s }), nil
+	}))
+	pipeline := func(factor int) *declarative.Definition {
+		def, err := declarative.Parse("pipeline.json", []byte(`{
+  "nodes": [
+    {"name": "feed", "type": "feed"},
+    {"name": "multiply", "type": "multiplier", "config": {"factor": `+strconv.Itoa(factor)+`}},
+    {"name": "stars", "type": "toString", "config": {}},
+    {"name": "notify", "type": "notifier"}
+  ],
+  "edges": [
+    {"from": "feed", "to": "multiply"},
+    {"from": "multiply", "to": "stars"},
+    {"from": "stars", "to": "notify"}
+  ]
+}`))
+		require.NoError(t, err)
+		return def
+	}
+
+	previous := pipeline(2)
+	runner, err := r.Build(previous, pipe.Reconfigurable())
+	require.NoError(t, err)
+	runner.Start()
+	input <- 1
+	assert.Equal(t, "**", helpers.ReadChannel(t, output, timeout))
+
+	next := pipeline(3)
+	changed := declarative.Changed(previous, next)
+	assert.Equal(t, []string{"multiply"}, changed)
+	nextRunner, err := r.Build(next)
+	require.NoError(t, err)
+	require.NoError(t, runner.Reconfigure(nextRunner, changed...))
+	input <- 1
+	assert.Equal(t, "***", helpers.ReadChannel(t, output, timeout))
+
+	close(input)
+	require.NoError(t, runner.Wait())
+}
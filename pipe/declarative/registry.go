@@ -0,0 +1,294 @@
+// Package declarative builds pipelines from a Definition that is parsed from a JSON document,
+// instead of a hand-written pipe.NodesMap. The node types are registered by name in a Registry,
+// along with a provider function that creates the node function from its typed configuration:
+//
+//	type GrepConfig struct {
+//		Pattern string `json:"pattern"`
+//	}
+//	registry := declarative.NewRegistry()
+//	err := declarative.RegisterMiddle(registry, "grep", func(cfg GrepConfig) (pipe.MiddleFunc[string, string], error) {
+//		matcher, err := regexp.Compile(cfg.Pattern)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return combinators.Filter(matcher.MatchString), nil
+//	})
+//	...
+//	def, err := declarative.Parse("pipeline.json", document)
+//	...
+//	runner, err := registry.Build(def)
+//
+// See the Parse function for the format of the JSON document. A base document can be merged with
+// per-environment overlays (see Load and Merge), and the errors point to the document that set the
+// offending value.
+package declarative
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+// Registry of the node types that can be instantiated from a pipeline Definition.
+type Registry struct {
+	types map[string]*nodeType
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{types: map[string]*nodeType{}}
+}
+
+// StartProvider is a function that returns a pipe.StartFunc from a configuration.
+// If both the returned function and the error are nil, the node is ignored (see pipe.StartProvider).
+type StartProvider[CFG, OUT any] func(cfg CFG) (pipe.StartFunc[OUT], error)
+
+// MiddleProvider is a function that returns a pipe.MiddleFunc from a configuration.
+// If both the returned function and the error are nil, the node is bypassed (see pipe.MiddleProvider).
+type MiddleProvider[CFG, IN, OUT any] func(cfg CFG) (pipe.MiddleFunc[IN, OUT], error)
+
+// FinalProvider is a function that returns a pipe.FinalFunc from a configuration.
+// If both the returned function and the error are nil, the node is ignored (see pipe.FinalProvider).
+type FinalProvider[CFG, IN any] func(cfg CFG) (pipe.FinalFunc[IN], error)
+
+// ErrDuplicateType is returned when a node type is registered twice with the same name.
+var ErrDuplicateType = errors.New("node type is already registered")
+
+// ErrDuplicateNode is returned when two node instances of a Definition have the same name.
+var ErrDuplicateNode = errors.New("duplicate node name")
+
+// NodeType describes a node type that has been registered in a Registry.
+type NodeType struct {
+	Name string
+	Kind pipe.NodeKind
+	// Config is the type of the configuration of the node instances.
+	Config reflect.Type
+	// In is the type of the input of the node. It is nil for Start nodes.
+	In reflect.Type
+	// Out is the type of the output of the node. It is nil for Final nodes.
+	Out reflect.Type
+}
+
+// nodeType hides the generic types of the registered providers, so node types with
+// different configuration, input and output types can be stored in the same Registry
+type nodeType struct {
+	NodeType
+	// add decodes the configuration of the node instance, and adds it to the builder
+	add func(nodes *nodesMap, node *Node, opts []pipe.Option) error
+	// connect sends the output of the src node instance to the input of the dst
+	// node instance, which must be of this type
+	connect func(src, dst any)
+}
+
+// register the node type. If its name is empty, the node type is named after its configuration type
+func (r *Registry) register(nt *nodeType) error {
+	namedByConfig := nt.Name == ""
+	if namedByConfig {
+		nt.Name = nt.Config.Name()
+		if nt.Name == "" {
+			return fmt.Errorf("a name is required to register a node type with an unnamed configuration type (%s)", nt.Config)
+		}
+	}
+	if existing, ok := r.types[nt.Name]; ok {
+		if namedByConfig {
+			return fmt.Errorf("%w: %q, as %s node. Define a different configuration type (e.g. type Other%s %s)"+
+				" or register the node type with an explicit name", ErrDuplicateType, nt.Name, existing.Kind, nt.Name, nt.Config)
+		}
+		return fmt.Errorf("%w: %q, as %s node", ErrDuplicateType, nt.Name, existing.Kind)
+	}
+	r.types[nt.Name] = nt
+	return nil
+}
+
+// Types returns the node types that have been registered, sorted by name.
+func (r *Registry) Types() []NodeType {
+	types := make([]NodeType, 0, len(r.types))
+	for _, nt := range r.types {
+		types = append(types, nt.NodeType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// RegisterStart registers a Start node type with the given name. If the name is empty, the node
+// type is named after the CFG type (e.g. "GrepConfig"). The configuration of the node instances is
+// decoded into the CFG type, and passed to the provider.
+// It returns an error wrapping ErrDuplicateType if the name has been already registered.
+func RegisterStart[CFG, OUT any](r *Registry, typeName string, provider StartProvider[CFG, OUT]) error {
+	return r.register(&nodeType{
+		NodeType: NodeType{Name: typeName, Kind: pipe.StartKind, Config: typeOf[CFG](), Out: typeOf[OUT]()},
+		add: func(nodes *nodesMap, node *Node, opts []pipe.Option) error {
+			cfg, err := decodeConfig[CFG](node)
+			if err != nil {
+				return err
+			}
+			name, enabled := node.Name, node.enabled()
+			nodes.slots[name] = new(pipe.Start[OUT])
+			pipe.AddStartProvider(nodes.builder, func(n *nodesMap) *pipe.Start[OUT] {
+				return n.slots[name].(*pipe.Start[OUT])
+			}, func() (pipe.StartFunc[OUT], error) {
+				if !enabled {
+					return nil, nil
+				}
+				return provider(cfg)
+			}, opts...)
+			return nil
+		},
+	})
+}
+
+// RegisterMiddle registers a Middle node type with the given name. If the name is empty, the node
+// type is named after the CFG type (e.g. "GrepConfig"). The configuration of the node instances is
+// decoded into the CFG type, and passed to the provider.
+// It returns an error wrapping ErrDuplicateType if the name has been already registered.
+func RegisterMiddle[CFG, IN, OUT any](r *Registry, typeName string, provider MiddleProvider[CFG, IN, OUT]) error {
+	return r.register(&nodeType{
+		NodeType: NodeType{Name: typeName, Kind: pipe.MiddleKind, Config: typeOf[CFG](), In: typeOf[IN](), Out: typeOf[OUT]()},
+		add: func(nodes *nodesMap, node *Node, opts []pipe.Option) error {
+			cfg, err := decodeConfig[CFG](node)
+			if err != nil {
+				return err
+			}
+			name, enabled := node.Name, node.enabled()
+			nodes.slots[name] = new(pipe.Middle[IN, OUT])
+			pipe.AddMiddleProvider(nodes.builder, func(n *nodesMap) *pipe.Middle[IN, OUT] {
+				return n.slots[name].(*pipe.Middle[IN, OUT])
+			}, func() (pipe.MiddleFunc[IN, OUT], error) {
+				if !enabled {
+					return nil, nil
+				}
+				return provider(cfg)
+			}, opts...)
+			return nil
+		},
+		connect: func(src, dst any) {
+			sender(src).(pipe.Sender[IN]).SendTo(*dst.(*pipe.Middle[IN, OUT]))
+		},
+	})
+}
+
+// RegisterFinal registers a Final node type with the given name. If the name is empty, the node
+// type is named after the CFG type (e.g. "GrepConfig"). The configuration of the node instances is
+// decoded into the CFG type, and passed to the provider.
+// It returns an error wrapping ErrDuplicateType if the name has been already registered.
+func RegisterFinal[CFG, IN any](r *Registry, typeName string, provider FinalProvider[CFG, IN]) error {
+	return r.register(&nodeType{
+		NodeType: NodeType{Name: typeName, Kind: pipe.FinalKind, Config: typeOf[CFG](), In: typeOf[IN]()},
+		add: func(nodes *nodesMap, node *Node, opts []pipe.Option) error {
+			cfg, err := decodeConfig[CFG](node)
+			if err != nil {
+				return err
+			}
+			name, enabled := node.Name, node.enabled()
+			nodes.slots[name] = new(pipe.Final[IN])
+			pipe.AddFinalProvider(nodes.builder, func(n *nodesMap) *pipe.Final[IN] {
+				return n.slots[name].(*pipe.Final[IN])
+			}, func() (pipe.FinalFunc[IN], error) {
+				if !enabled {
+					return nil, nil
+				}
+				return provider(cfg)
+			}, opts...)
+			return nil
+		},
+		connect: func(src, dst any) {
+			sender(src).(pipe.Sender[IN]).SendTo(*dst.(*pipe.Final[IN]))
+		},
+	})
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// sender returns the node that is stored in a *pipe.Start or *pipe.Middle pointer
+func sender(src any) any {
+	return reflect.ValueOf(src).Elem().Interface()
+}
+
+// nodesMap is a pipe.NamedNodesMap whose nodes are defined at runtime
+type nodesMap struct {
+	builder *pipe.Builder[*nodesMap]
+	// slots stores, by node name, a pointer to a pipe.Start, pipe.Middle or pipe.Final
+	slots map[string]any
+	// connections to be run by the Connect method
+	connections []func()
+}
+
+func (n *nodesMap) Connect() {
+	for _, connect := range n.connections {
+		connect()
+	}
+}
+
+func (n *nodesMap) NodeNames() map[any]string {
+	names := make(map[any]string, len(n.slots))
+	for name, ptr := range n.slots {
+		names[ptr] = name
+	}
+	return names
+}
+
+// Build a pipe.Runner from the pipeline Definition, instantiating the node types of the Registry.
+// The options are passed to the pipe.NewBuilder function.
+// Any error in the definition (e.g. unknown node types or names, invalid configurations, or
+// edges between nodes with incompatible types) is returned as an *Error. The errors that are
+// detected when the pipeline is built are returned as-is (e.g. a *pipe.ValidationError).
+func (r *Registry) Build(def *Definition, opts ...pipe.Option) (*pipe.Runner, error) {
+	nodes := &nodesMap{slots: map[string]any{}}
+	nodes.builder = pipe.NewBuilder(nodes, opts...)
+	types := map[string]*nodeType{}
+	for i := range def.Nodes {
+		node := &def.Nodes[i]
+		if node.Type == "" {
+			return nil, node.pos.errorf("missing type of node %q", node.Name)
+		}
+		nt, ok := r.types[node.Type]
+		if !ok {
+			return nil, node.typePos.errorf("unknown type %q of node %q", node.Type, node.Name)
+		}
+		if _, ok := types[node.Name]; ok {
+			return nil, node.pos.errorf("%w: %q", ErrDuplicateNode, node.Name)
+		}
+		types[node.Name] = nt
+		if !node.enabled() && nt.Kind == pipe.MiddleKind && nt.In != nt.Out {
+			return nil, node.enabledPos.errorf("Middle node %q can't be disabled, as its input type (%s) and output type (%s) differ",
+				node.Name, nt.In, nt.Out)
+		}
+		var nodeOpts []pipe.Option
+		if node.BufferLength > 0 {
+			nodeOpts = append(nodeOpts, pipe.ChannelBufferLen(node.BufferLength))
+		}
+		if err := nt.add(nodes, node, nodeOpts); err != nil {
+			return nil, err
+		}
+	}
+	for _, edge := range def.Edges {
+		src, ok := types[edge.From]
+		if !ok {
+			return nil, edge.pos.errorf("undefined source node %q", edge.From)
+		}
+		dst, ok := types[edge.To]
+		if !ok {
+			return nil, edge.pos.errorf("undefined destination node %q", edge.To)
+		}
+		if src.Kind == pipe.FinalKind {
+			return nil, edge.pos.errorf("%s node %q can't send data to other nodes", src.Kind, edge.From)
+		}
+		if dst.Kind == pipe.StartKind {
+			return nil, edge.pos.errorf("%s node %q can't receive data from other nodes", dst.Kind, edge.To)
+		}
+		if src.Out != dst.In {
+			return nil, edge.pos.errorf("can't connect node %q (output type %s) to node %q (input type %s)",
+				edge.From, src.Out, edge.To, dst.In)
+		}
+		srcPtr, dstPtr, connect := nodes.slots[edge.From], nodes.slots[edge.To], dst.connect
+		nodes.connections = append(nodes.connections, func() {
+			connect(srcPtr, dstPtr)
+		})
+	}
+	return nodes.builder.Build()
+}
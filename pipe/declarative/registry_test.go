@@ -0,0 +1,99 @@
+package declarative_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/combinators"
+	"github.com/mariomac/pipes/pipe/declarative"
+)
+
+type PrinterConfig struct {
+	Prefix string `json:"prefix"`
+}
+
+// OtherPrinterConfig allows registering another node type with the same configuration
+type OtherPrinterConfig PrinterConfig
+
+func printer(PrinterConfig) (pipe.FinalFunc[string], error) {
+	return combinators.ForEach(func(string) {}), nil
+}
+
+func TestRegistry_Duplicates(t *testing.T) {
+	var results []string
+	r := testRegistry(t, &results)
+
+	// registering against the configuration type
+	require.NoError(t, declarative.RegisterFinal(r, "", printer))
+	err := declarative.RegisterFinal(r, "", printer)
+	require.ErrorIs(t, err, declarative.ErrDuplicateType)
+	assert.Contains(t, err.Error(), `"PrinterConfig", as Final node. Define a different configuration type`)
+	require.NoError(t, declarative.RegisterFinal(r, "", func(cfg OtherPrinterConfig) (pipe.FinalFunc[string], error) {
+		return printer(PrinterConfig(cfg))
+	}))
+
+	// registering against a name
+	require.NoError(t, declarative.RegisterFinal(r, "printer", printer))
+	err = declarative.RegisterMiddle(r, "multiplier", func(multiplierConfig) (pipe.MiddleFunc[int, int], error) {
+		return nil, nil
+	})
+	require.ErrorIs(t, err, declarative.ErrDuplicateType)
+	assert.Contains(t, err.Error(), `"multiplier", as Middle node`)
+
+	// unnamed configuration types require a name
+	require.Error(t, declarative.RegisterStart(r, "", func(struct{}) (pipe.StartFunc[int], error) {
+		return nil, nil
+	}))
+}
+
+func TestRegistry_Types(t *testing.T) {
+	var results []string
+	r := testRegistry(t, &results)
+	require.NoError(t, declarative.RegisterFinal(r, "", printer))
+
+	intType, stringType := reflect.TypeOf(0), reflect.TypeOf("")
+	assert.Equal(t, []declarative.NodeType{
+		{Name: "PrinterConfig", Kind: pipe.FinalKind, Config: reflect.TypeOf(PrinterConfig{}), In: stringType},
+		{Name: "collector", Kind: pipe.FinalKind, Config: reflect.TypeOf(struct{}{}), In: stringType},
+		{Name: "counter", Kind: pipe.StartKind, Config: reflect.TypeOf(counterConfig{}), Out: intType},
+		{Name: "multiplier", Kind: pipe.MiddleKind, Config: reflect.TypeOf(multiplierConfig{}), In: intType, Out: intType},
+		{Name: "toString", Kind: pipe.MiddleKind, Config: reflect.TypeOf(struct{}{}), In: intType, Out: stringType},
+	}, r.Types())
+}
+
+func TestBuild_InstanceIDs(t *testing.T) {
+	var results []string
+	r := testRegistry(t, &results)
+
+	// the same node type can be instantiated many times
+	def := &declarative.Definition{
+		Nodes: []declarative.Node{
+			{Name: "count", Type: "counter", Config: []byte(`{"from": 1, "to": 2}`)},
+			{Name: "triple", Type: "multiplier", Config: []byte(`{"factor": 3}`)},
+			{Name: "double", Type: "multiplier", Config: []byte(`{"factor": 2}`)},
+			{Name: "stars", Type: "toString"},
+			{Name: "collect", Type: "collector"},
+		},
+		Edges: []declarative.Edge{
+			{From: "count", To: "triple"},
+			{From: "triple", To: "double"},
+			{From: "double", To: "stars"},
+			{From: "stars", To: "collect"},
+		},
+	}
+	runner, err := r.Build(def)
+	require.NoError(t, err)
+	runner.Start()
+	require.NoError(t, runner.Wait())
+	assert.Equal(t, []string{"******", "************"}, results)
+
+	// but their instance IDs must be unique
+	def.Nodes = append(def.Nodes, declarative.Node{Name: "double", Type: "multiplier"})
+	_, err = r.Build(def)
+	require.ErrorIs(t, err, declarative.ErrDuplicateNode)
+	assert.Equal(t, `duplicate node name: "double"`, err.Error())
+}
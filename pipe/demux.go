@@ -0,0 +1,573 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// Demux provides access to the named outputs of a StartDemux or MiddleDemux node.
+// Each output can have a different type.
+type Demux struct {
+	*demuxChannels
+}
+
+// DemuxGet returns the output channel with the given name and type, as it was declared in the
+// NodesMap Connect method by means of the DemuxAdd function.
+// If the output hasn't been declared, or it was declared with a different type, the node
+// reports an error to the Runner (see Runner.Err), and the returned channel discards all
+// the data that is sent to it.
+func DemuxGet[OUT any](d Demux, name string) chan<- OUT {
+	if ch, ok := d.outs[name].(chan<- OUT); ok {
+		return ch
+	}
+	d.mt.Lock()
+	defer d.mt.Unlock()
+	if ch, ok := d.discarded[name].(chan<- OUT); ok {
+		return ch
+	}
+	d.report(fmt.Errorf("DemuxGet: output %q of type %s has not been declared", name, typeOf[OUT]()))
+	ch := make(chan OUT)
+	go drain(ch)
+	d.discarded[name] = (chan<- OUT)(ch)
+	d.closers = append(d.closers, func() { close(ch) })
+	return ch
+}
+
+// DemuxAdd declares an output of a StartDemux or MiddleDemux node, with the given name and type,
+// and returns a Sender that allows connecting it to other nodes. It must be invoked from
+// the NodesMap Connect method. The function of the node gets the output channel by means
+// of the DemuxGet function.
+// The Builder returns a *ValidationError if any declared output is not connected to any
+// node, or if an output is declared twice with different types.
+func DemuxAdd[OUT any](node Demuxer, name string) Sender[OUT] {
+	if node == nil {
+		return &demuxOutput[OUT]{}
+	}
+	outs := node.demuxOutputs()
+	if outs == nil {
+		// an ignored node can be connected without any effect on the pipeline
+		return &demuxOutput[OUT]{}
+	}
+	if existing, ok := outs.byName[name]; ok {
+		if sender, ok := existing.(*demuxOutput[OUT]); ok {
+			return sender
+		}
+		outs.conflicting = append(outs.conflicting, name)
+		return &demuxOutput[OUT]{}
+	}
+	sender := &demuxOutput[OUT]{}
+	outs.names = append(outs.names, name)
+	outs.byName[name] = sender
+	return sender
+}
+
+// Demuxer is any node that sends data to multiple named outputs: StartDemux or MiddleDemux.
+type Demuxer interface {
+	demuxOutputs() *demuxOutputs
+}
+
+// StartDemux nodes are Start nodes that send data to multiple named outputs. Outputs are
+// declared and connected by means of the DemuxAdd function.
+type StartDemux interface {
+	Demuxer
+}
+
+// MiddleDemux nodes are Middle nodes that send data to multiple named outputs. Outputs are
+// declared and connected by means of the DemuxAdd function.
+type MiddleDemux[IN any] interface {
+	Receiver[IN]
+	Demuxer
+}
+
+// StartDemuxFunc is a StartFunc that sends data to the named outputs of the Demux argument.
+// The channel of each output is accessed through the DemuxGet function.
+type StartDemuxFunc func(out Demux)
+
+// MiddleDemuxFunc is a MiddleFunc that sends data to the named outputs of the Demux argument.
+// The channel of each output is accessed through the DemuxGet function.
+// It must process the inputs from the input channel until it's closed.
+type MiddleDemuxFunc[IN any] func(in <-chan IN, out Demux)
+
+// StartDemuxFuncCtx is a StartDemuxFunc that also receives, as first argument, the context of the
+// Runner. The function should return when the context is cancelled.
+type StartDemuxFuncCtx func(ctx context.Context, out Demux)
+
+// MiddleDemuxFuncCtx is a MiddleDemuxFunc that also receives, as first argument, the context of the
+// Runner. Despite the context is cancelled, the function should keep processing the
+// inputs from the input channel until it's closed, so the pipeline is properly drained.
+type MiddleDemuxFuncCtx[IN any] func(ctx context.Context, in <-chan IN, out Demux)
+
+// demuxOutputs stores the outputs that are declared by DemuxAdd
+type demuxOutputs struct {
+	// names of the outputs, in declaration order
+	names  []string
+	byName map[string]demuxSender
+	// conflicting stores the names of the outputs that have been declared with different types
+	conflicting []string
+}
+
+func newDemuxOutputs() demuxOutputs {
+	return demuxOutputs{byName: map[string]demuxSender{}}
+}
+
+// demuxSender provides type-agnostic access to a demuxOutput
+type demuxSender interface {
+	destinations() []pipeNode
+	feedbackDestinations() []pipeNode
+	outType() reflect.Type
+	// keyMatches returns whether the FanOutByKey function, if any, accepts the output type
+	keyMatches(fo fanOut) bool
+	// connect starts the receivers of the output and returns the output channel,
+	// and the function that releases it
+	connect(rs *runState, stats *connect.Stats, fo fanOut, loop *connect.Loop) (out any, release func(), err error)
+}
+
+// demuxOutput is a named output of a demux node
+type demuxOutput[OUT any] struct {
+	receiverGroup[OUT]
+}
+
+func (d *demuxOutput[OUT]) destinations() []pipeNode {
+	return asPipeNodes(d.Outs)
+}
+
+func (d *demuxOutput[OUT]) feedbackDestinations() []pipeNode {
+	return asPipeNodes(d.feedbackOuts)
+}
+
+func (d *demuxOutput[OUT]) outType() reflect.Type {
+	return typeOf[OUT]()
+}
+
+func (d *demuxOutput[OUT]) connect(
+	rs *runState, stats *connect.Stats, fo fanOut, loop *connect.Loop,
+) (any, func(), error) {
+	forker, err := startReceivers(rs, stats, fo, loop, d.Outs, d.feedbackOuts)
+	if forker == nil {
+		return nil, nil, err
+	}
+	return (chan<- OUT)(forker.AcquireSender()), forker.ReleaseSender, err
+}
+
+func (o *demuxOutputs) destinations() []pipeNode {
+	var dsts []pipeNode
+	for _, name := range o.names {
+		dsts = append(dsts, o.byName[name].destinations()...)
+	}
+	return dsts
+}
+
+func (o *demuxOutputs) feedbackDestinations() []pipeNode {
+	var dsts []pipeNode
+	for _, name := range o.names {
+		dsts = append(dsts, o.byName[name].feedbackDestinations()...)
+	}
+	return dsts
+}
+
+// demuxChannels stores the output channels of a running demux node
+type demuxChannels struct {
+	outs     map[string]any
+	releases []func()
+	report   func(error)
+
+	// discarded channels are returned by DemuxGet for undeclared outputs
+	mt        sync.Mutex
+	discarded map[string]any
+	closers   []func()
+}
+
+// connect all the outputs of a demux node. Errors are reported to the Runner
+func (o *demuxOutputs) connect(rs *runState, name string, stats *connect.Stats, fo fanOut, loop *connect.Loop) Demux {
+	d := Demux{&demuxChannels{
+		outs:      map[string]any{},
+		report:    func(err error) { rs.nodeError(name, err) },
+		discarded: map[string]any{},
+	}}
+	names := append([]string{}, o.names...)
+	sort.Strings(names)
+	for _, out := range names {
+		ch, release, err := o.byName[out].connect(rs, stats, fo, loop)
+		if err != nil {
+			rs.nodeError(name, fmt.Errorf("output %q: %w", out, err))
+		}
+		if ch != nil {
+			d.outs[out] = ch
+			d.releases = append(d.releases, release)
+		}
+	}
+	return d
+}
+
+func (d Demux) release() {
+	for _, release := range d.releases {
+		release()
+	}
+	d.mt.Lock()
+	defer d.mt.Unlock()
+	for _, closer := range d.closers {
+		closer()
+	}
+}
+
+// startDemux is a start node with multiple named outputs
+type startDemux struct {
+	name        string
+	outputs     demuxOutputs
+	fun         func(ctx context.Context, out Demux) error
+	panicPolicy PanicPolicy
+	stats       *connect.Stats
+	fanOut      fanOut
+}
+
+func asStartDemux(fun StartDemuxFunc, opts ...Option) *startDemux {
+	if fun == nil {
+		return nil
+	}
+	return newStartDemux(func(_ context.Context, out Demux) error {
+		fun(out)
+		return nil
+	}, opts...)
+}
+
+func asStartDemuxCtx(fun StartDemuxFuncCtx, opts ...Option) *startDemux {
+	if fun == nil {
+		return nil
+	}
+	return newStartDemux(func(ctx context.Context, out Demux) error {
+		fun(ctx, out)
+		return nil
+	}, opts...)
+}
+
+func newStartDemux(fun func(ctx context.Context, out Demux) error, opts ...Option) *startDemux {
+	options := getOptions(opts...)
+	return &startDemux{
+		outputs:     newDemuxOutputs(),
+		fun:         fun,
+		panicPolicy: options.panicPolicy,
+		stats:       newStats(&options),
+		fanOut:      fanOutOf(&options),
+	}
+}
+
+func (sd *startDemux) demuxOutputs() *demuxOutputs {
+	if sd == nil {
+		return nil
+	}
+	return &sd.outputs
+}
+
+func (sd *startDemux) setName(name string) {
+	if sd != nil {
+		sd.name = name
+	}
+}
+
+func (sd *startDemux) nodeName() string {
+	if sd == nil {
+		return ""
+	}
+	return sd.name
+}
+
+func (sd *startDemux) kind() nodeKind {
+	return startKind
+}
+
+func (sd *startDemux) isNil() bool {
+	return sd == nil
+}
+
+func (sd *startDemux) destinations() []pipeNode {
+	if sd == nil {
+		return nil
+	}
+	return sd.outputs.destinations()
+}
+
+func (sd *startDemux) feedbackDestinations() []pipeNode {
+	if sd == nil {
+		return nil
+	}
+	return sd.outputs.feedbackDestinations()
+}
+
+func (sd *startDemux) describe() NodeInfo {
+	return NodeInfo{Name: sd.name, Kind: StartKind}
+}
+
+func (sd *startDemux) nodeStats() NodeStats {
+	return snapshot(sd.stats)
+}
+
+func (sd *startDemux) start(rs *runState) {
+	if sd == nil {
+		return
+	}
+	out := sd.outputs.connect(rs, sd.name, sd.stats, sd.fanOut, nil)
+	rs.run(func() {
+		rs.runNode(sd.name, sd.panicPolicy, func() error {
+			return sd.fun(rs.ctx, out)
+		})
+		out.release()
+	})
+}
+
+// middleDemux is a middle node with multiple named outputs
+type middleDemux[IN any] struct {
+	name        string
+	inputs      connect.Joiner[IN]
+	started     bool
+	outputs     demuxOutputs
+	fun         func(ctx context.Context, in <-chan IN, out Demux) error
+	panicPolicy PanicPolicy
+	stats       *connect.Stats
+	fanOut      fanOut
+	// loop is set if the node is part of a feedback loop
+	loop *connect.Loop
+	// spillMismatch is set if the OnOverflowSpill function does not accept the input type
+	spillMismatch bool
+}
+
+func asMiddleDemux[IN any](fun MiddleDemuxFunc[IN], opts ...Option) *middleDemux[IN] {
+	return newMiddleDemux(func(_ context.Context, in <-chan IN, out Demux) error {
+		fun(in, out)
+		return nil
+	}, opts...)
+}
+
+func asMiddleDemuxCtx[IN any](fun MiddleDemuxFuncCtx[IN], opts ...Option) *middleDemux[IN] {
+	return newMiddleDemux(func(ctx context.Context, in <-chan IN, out Demux) error {
+		fun(ctx, in, out)
+		return nil
+	}, opts...)
+}
+
+func newMiddleDemux[IN any](fun func(ctx context.Context, in <-chan IN, out Demux) error, opts ...Option) *middleDemux[IN] {
+	options := getOptions(opts...)
+	m := &middleDemux[IN]{
+		inputs:      connect.NewJoiner[IN](options.channelBufferLen),
+		outputs:     newDemuxOutputs(),
+		fun:         fun,
+		panicPolicy: options.panicPolicy,
+		stats:       newStats(&options),
+		fanOut:      fanOutOf(&options),
+	}
+	m.spillMismatch = !setOverflow(&m.inputs, &options)
+	return m
+}
+
+func (m *middleDemux[IN]) demuxOutputs() *demuxOutputs {
+	return &m.outputs
+}
+
+func (m *middleDemux[IN]) setName(name string) {
+	m.name = name
+}
+
+func (m *middleDemux[IN]) nodeName() string {
+	return m.name
+}
+
+func (m *middleDemux[IN]) kind() nodeKind {
+	return middleKind
+}
+
+func (m *middleDemux[IN]) isNil() bool {
+	return false
+}
+
+func (m *middleDemux[IN]) destinations() []pipeNode {
+	return m.outputs.destinations()
+}
+
+func (m *middleDemux[IN]) feedbackDestinations() []pipeNode {
+	return m.outputs.feedbackDestinations()
+}
+
+func (m *middleDemux[IN]) describe() NodeInfo {
+	return NodeInfo{
+		Name:             m.name,
+		Kind:             MiddleKind,
+		In:               typeOf[IN](),
+		ChannelBufferLen: m.inputs.BufferLen(),
+	}
+}
+
+func (m *middleDemux[IN]) nodeStats() NodeStats {
+	return snapshot(m.stats, &m.inputs)
+}
+
+func (m *middleDemux[IN]) joiners() []*connect.Joiner[IN] {
+	return []*connect.Joiner[IN]{&m.inputs}
+}
+
+func (m *middleDemux[IN]) feedbackJoiners() []*connect.Joiner[IN] {
+	return []*connect.Joiner[IN]{m.inputs.Feedback()}
+}
+
+func (m *middleDemux[IN]) setLoop(l *connect.Loop) {
+	m.loop = l
+	m.inputs.SetLoop(l)
+}
+
+func (m *middleDemux[IN]) isStarted() bool {
+	return m.started
+}
+
+func (m *middleDemux[IN]) start(rs *runState) {
+	m.started = true
+	in := receiver(&m.inputs, m.stats)
+	out := m.outputs.connect(rs, m.name, m.stats, m.fanOut, m.loop)
+	rs.run(func() {
+		rs.runNode(m.name, m.panicPolicy, func() error {
+			defer m.loop.Abort()
+			return m.fun(rs.ctx, in, out)
+		})
+		out.release()
+		go drain(in)
+	})
+}
+
+// StartDemuxPtr is a function that, given a NodesMap, returns a pointer to a
+// StartDemux node, which is going to be used as store destination
+// when this function is passed as argument to AddStartDemux or
+// AddStartDemuxProvider functions.
+type StartDemuxPtr[IMPL NodesMap] func(IMPL) *StartDemux
+
+// MiddleDemuxPtr is a function that, given a NodesMap, returns a pointer to a
+// MiddleDemux node, which is going to be used as store destination
+// when this function is passed as argument to AddMiddleDemux or
+// AddMiddleDemuxProvider functions.
+type MiddleDemuxPtr[IMPL NodesMap, IN any] func(IMPL) *MiddleDemux[IN]
+
+// StartDemuxProvider is a function that returns a StartDemuxFunc to be used as
+// StartDemux node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// If both the returned function and the error are nil, the node will be ignored.
+type StartDemuxProvider func() (StartDemuxFunc, error)
+
+// MiddleDemuxProvider is a function that returns a MiddleDemuxFunc to be used as
+// MiddleDemux node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// The returned function can't be nil unless an error is returned.
+type MiddleDemuxProvider[IN any] func() (MiddleDemuxFunc[IN], error)
+
+// StartDemuxProviderCtx is a StartDemuxProvider that returns a StartDemuxFuncCtx.
+type StartDemuxProviderCtx func() (StartDemuxFuncCtx, error)
+
+// MiddleDemuxProviderCtx is a MiddleDemuxProvider that returns a MiddleDemuxFuncCtx.
+type MiddleDemuxProviderCtx[IN any] func() (MiddleDemuxFuncCtx[IN], error)
+
+// AddStartDemux creates a StartDemux node given the provided StartDemuxFunc. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided StartDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartDemux[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], fn StartDemuxFunc, opts ...Option) {
+	addStartDemux(p, field, asStartDemux(fn, p.joinOpts(opts...)...))
+}
+
+// AddStartDemuxCtx creates a StartDemux node given the provided StartDemuxFuncCtx. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided StartDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartDemuxCtx[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], fn StartDemuxFuncCtx, opts ...Option) {
+	addStartDemux(p, field, asStartDemuxCtx(fn, p.joinOpts(opts...)...))
+}
+
+func addStartDemux[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], startNode *startDemux) {
+	dstAddress := field(p.nodesMap)
+	p.startNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[startable]{node: startNode}
+	*(dstAddress) = startNode
+}
+
+// AddStartDemuxProvider registers a StartDemuxProvider into the pipeline Builder.
+// The function returned by the StartDemuxProvider will be assigned to the NodesMap
+// field whose pointer is returned by the passed StartDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartDemuxProvider[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], provider StartDemuxProvider, opts ...Option) {
+	addStartDemuxProvider(p, field, asStartDemux, provider, opts...)
+}
+
+// AddStartDemuxProviderCtx registers a StartDemuxProviderCtx into the pipeline Builder.
+// The function returned by the StartDemuxProviderCtx will be assigned to the NodesMap
+// field whose pointer is returned by the passed StartDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartDemuxProviderCtx[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], provider StartDemuxProviderCtx, opts ...Option) {
+	addStartDemuxProvider(p, field, asStartDemuxCtx, provider, opts...)
+}
+
+func addStartDemuxProvider[IMPL NodesMap](p *Builder[IMPL], field StartDemuxPtr[IMPL], asNode, provider any, opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.startNodes[dstAddress] = nodeOrProvider[startable]{
+		provider: &reflectProvider{
+			acceptNilFunc: true,
+			asNode:        reflect.ValueOf(asNode),
+			fieldGetter:   reflect.ValueOf(field),
+			fn:            reflect.ValueOf(provider),
+			opts:          p.joinOpts(opts...),
+		}}
+}
+
+// AddMiddleDemux creates a MiddleDemux node given the provided MiddleDemuxFunc. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided MiddleDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddleDemux[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], fn MiddleDemuxFunc[IN], opts ...Option) {
+	addMiddleDemux(p, field, asMiddleDemux(fn, p.joinOpts(opts...)...))
+}
+
+// AddMiddleDemuxCtx creates a MiddleDemux node given the provided MiddleDemuxFuncCtx. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided MiddleDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddleDemuxCtx[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], fn MiddleDemuxFuncCtx[IN], opts ...Option) {
+	addMiddleDemux(p, field, asMiddleDemuxCtx(fn, p.joinOpts(opts...)...))
+}
+
+func addMiddleDemux[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], middleNode *middleDemux[IN]) {
+	dstAddress := field(p.nodesMap)
+	p.middleNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[pipeNode]{node: middleNode}
+	*(dstAddress) = middleNode
+}
+
+// AddMiddleDemuxProvider registers a MiddleDemuxProvider into the pipeline Builder.
+// The function returned by the MiddleDemuxProvider will be assigned to the NodesMap
+// field whose pointer is returned by the passed MiddleDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddleDemuxProvider[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], provider MiddleDemuxProvider[IN], opts ...Option) {
+	addMiddleDemuxProvider(p, field, asMiddleDemux[IN], provider, opts...)
+}
+
+// AddMiddleDemuxProviderCtx registers a MiddleDemuxProviderCtx into the pipeline Builder.
+// The function returned by the MiddleDemuxProviderCtx will be assigned to the NodesMap
+// field whose pointer is returned by the passed MiddleDemuxPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddleDemuxProviderCtx[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], provider MiddleDemuxProviderCtx[IN], opts ...Option) {
+	addMiddleDemuxProvider(p, field, asMiddleDemuxCtx[IN], provider, opts...)
+}
+
+func addMiddleDemuxProvider[IMPL NodesMap, IN any](p *Builder[IMPL], field MiddleDemuxPtr[IMPL, IN], asNode, provider any, opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.middleNodes[dstAddress] = nodeOrProvider[pipeNode]{
+		provider: &reflectProvider{
+			asNode:      reflect.ValueOf(asNode),
+			fieldGetter: reflect.ValueOf(field),
+			fn:          reflect.ValueOf(provider),
+			opts:        p.joinOpts(opts...),
+		}}
+}
@@ -0,0 +1,229 @@
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+type demuxPipe struct {
+	source pipe.StartDemux
+	router pipe.MiddleDemux[int]
+	evens  pipe.Final[int]
+	odds   pipe.Final[string]
+	errs   pipe.Final[error]
+}
+
+func (d *demuxPipe) Connect() {
+	pipe.DemuxAdd[int](d.source, "numbers").SendTo(d.router)
+	pipe.DemuxAdd[error](d.source, "errors").SendTo(d.errs)
+	pipe.DemuxAdd[int](d.router, "evens").SendTo(d.evens)
+	pipe.DemuxAdd[string](d.router, "odds").SendTo(d.odds)
+}
+
+type demuxResults struct {
+	evens []int
+	odds  []string
+	errs  []error
+}
+
+func demuxBuilder(res *demuxResults, router pipe.MiddleDemuxFunc[int]) *pipe.Builder[*demuxPipe] {
+	b := pipe.NewBuilder(&demuxPipe{})
+	pipe.AddStartDemux(b, func(d *demuxPipe) *pipe.StartDemux { return &d.source }, func(out pipe.Demux) {
+		numbers := pipe.DemuxGet[int](out, "numbers")
+		errs := pipe.DemuxGet[error](out, "errors")
+		for i := 1; i <= 5; i++ {
+			numbers <- i
+		}
+		errs <- errors.New("no more numbers")
+	})
+	pipe.AddMiddleDemuxProvider(b, func(d *demuxPipe) *pipe.MiddleDemux[int] { return &d.router },
+		func() (pipe.MiddleDemuxFunc[int], error) {
+			return router, nil
+		})
+	pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[int] { return &d.evens }, func(in <-chan int) {
+		for i := range in {
+			res.evens = append(res.evens, i)
+		}
+	})
+	pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[string] { return &d.odds }, func(in <-chan string) {
+		for i := range in {
+			res.odds = append(res.odds, i)
+		}
+	})
+	pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[error] { return &d.errs }, func(in <-chan error) {
+		for i := range in {
+			res.errs = append(res.errs, i)
+		}
+	})
+	return b
+}
+
+func evenOddRouter(in <-chan int, out pipe.Demux) {
+	evens := pipe.DemuxGet[int](out, "evens")
+	odds := pipe.DemuxGet[string](out, "odds")
+	for i := range in {
+		if i%2 == 0 {
+			evens <- i
+		} else {
+			odds <- strconv.Itoa(i)
+		}
+	}
+}
+
+func TestDemux(t *testing.T) {
+	res := demuxResults{}
+	r, err := demuxBuilder(&res, evenOddRouter).Build()
+	require.NoError(t, err)
+
+	r.Start()
+	require.NoError(t, r.Wait())
+	assert.Equal(t, []int{2, 4}, res.evens)
+	assert.Equal(t, []string{"1", "3", "5"}, res.odds)
+	assert.Equal(t, []error{errors.New("no more numbers")}, res.errs)
+
+	var edges []pipe.Edge
+	for _, e := range r.Graph().Edges {
+		if e.From == "router" {
+			edges = append(edges, e)
+		}
+	}
+	assert.Equal(t, []pipe.Edge{
+		{From: "router", To: "evens", Output: "evens", Type: reflect.TypeOf(0)},
+		{From: "router", To: "odds", Output: "odds", Type: reflect.TypeOf("")},
+	}, edges)
+}
+
+func TestDemux_Ctx(t *testing.T) {
+	// loops until the Runner is stopped
+	source := func(ctx context.Context, out pipe.Demux) {
+		numbers := pipe.DemuxGet[int](out, "numbers")
+		for i := 1; ; i++ {
+			select {
+			case numbers <- i:
+			case <-ctx.Done():
+				pipe.DemuxGet[error](out, "errors") <- ctx.Err()
+				return
+			}
+		}
+	}
+	var routerCtx context.Context
+	router := func(ctx context.Context, in <-chan int, out pipe.Demux) {
+		routerCtx = ctx
+		evenOddRouter(in, out)
+	}
+	for _, providers := range []bool{false, true} {
+		b := pipe.NewBuilder(&demuxPipe{})
+		sourcePtr := func(d *demuxPipe) *pipe.StartDemux { return &d.source }
+		routerPtr := func(d *demuxPipe) *pipe.MiddleDemux[int] { return &d.router }
+		if providers {
+			pipe.AddStartDemuxProviderCtx(b, sourcePtr, func() (pipe.StartDemuxFuncCtx, error) {
+				return source, nil
+			})
+			pipe.AddMiddleDemuxProviderCtx(b, routerPtr, func() (pipe.MiddleDemuxFuncCtx[int], error) {
+				return router, nil
+			})
+		} else {
+			pipe.AddStartDemuxCtx(b, sourcePtr, source)
+			pipe.AddMiddleDemuxCtx(b, routerPtr, router)
+		}
+		evens := make(chan int)
+		pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[int] { return &d.evens }, func(in <-chan int) {
+			for i := range in {
+				select {
+				case evens <- i:
+				default:
+				}
+			}
+		})
+		pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[string] { return &d.odds }, func(in <-chan string) {
+			for range in {
+			}
+		})
+		var errs []error
+		pipe.AddFinal(b, func(d *demuxPipe) *pipe.Final[error] { return &d.errs }, func(in <-chan error) {
+			for err := range in {
+				errs = append(errs, err)
+			}
+		})
+		r, err := b.Build()
+		require.NoError(t, err)
+
+		r.Start()
+		helpers.ReadChannel(t, evens, timeout)
+		r.Stop()
+		helpers.ReadChannel(t, r.Done(), timeout)
+		require.NoError(t, r.Err())
+		assert.Equal(t, []error{context.Canceled}, errs)
+		assert.Equal(t, context.Canceled, routerCtx.Err())
+	}
+}
+
+func TestDemux_UndeclaredOutput(t *testing.T) {
+	res := demuxResults{}
+	r, err := demuxBuilder(&res, func(in <-chan int, out pipe.Demux) {
+		evens := pipe.DemuxGet[int](out, "evens")
+		// wrong type
+		odds := pipe.DemuxGet[int](out, "odds")
+		for i := range in {
+			if i%2 == 0 {
+				evens <- i
+			} else {
+				odds <- i
+			}
+		}
+	}).Build()
+	require.NoError(t, err)
+
+	r.Start()
+	err = r.Wait()
+	assert.Equal(t, []int{2, 4}, res.evens)
+	assert.Empty(t, res.odds)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `node router: DemuxGet: output "odds" of type int has not been declared`)
+}
+
+type invalidDemuxPipe struct {
+	source pipe.StartDemux
+	final  pipe.Final[int]
+}
+
+func (d *invalidDemuxPipe) Connect() {
+	pipe.DemuxAdd[int](d.source, "numbers").SendTo(d.final)
+	pipe.DemuxAdd[string](d.source, "numbers").SendTo()
+	pipe.DemuxAdd[string](d.source, "words")
+}
+
+func TestDemux_Validation(t *testing.T) {
+	b := pipe.NewBuilder(&invalidDemuxPipe{})
+	pipe.AddStartDemux(b, func(d *invalidDemuxPipe) *pipe.StartDemux { return &d.source }, func(_ pipe.Demux) {})
+	pipe.AddFinal(b, func(d *invalidDemuxPipe) *pipe.Final[int] { return &d.final }, func(in <-chan int) {})
+	_, err := b.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"source.words"}, verr.UnconnectedOutputs)
+	assert.Equal(t, []string{"source.numbers"}, verr.ConflictingOutputs)
+}
+
+func TestDemux_IgnoredStart(t *testing.T) {
+	b := pipe.NewBuilder(&invalidDemuxPipe{})
+	pipe.AddStartDemuxProvider(b, func(d *invalidDemuxPipe) *pipe.StartDemux { return &d.source },
+		func() (pipe.StartDemuxFunc, error) {
+			return nil, nil
+		})
+	pipe.AddFinal(b, func(d *invalidDemuxPipe) *pipe.Final[int] { return &d.final }, func(in <-chan int) {})
+	_, err := b.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	// the outputs of an ignored node are not validated, but its destinations become unreachable
+	assert.Equal(t, []string{"final"}, verr.Unreachable)
+	assert.Empty(t, verr.UnconnectedOutputs)
+}
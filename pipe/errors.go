@@ -0,0 +1,43 @@
+package pipe
+
+import (
+	"fmt"
+)
+
+// NodeError wraps an error that has been returned by a node of the pipeline.
+type NodeError struct {
+	// Node is the name of the node that returned the error. By default, it is the name
+	// of the NodesMap field where the node is stored.
+	Node string
+	// Err is the error returned by the node.
+	Err error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s: %s", e.Node, e.Err.Error())
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is reported to the Runner, wrapped into a *NodeError, when the function of a node
+// panics and its PanicPolicy allows recovering from it.
+type PanicError struct {
+	// Value passed to the panic invocation
+	Value any
+	// Stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to the panic invocation, if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
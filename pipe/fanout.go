@@ -0,0 +1,111 @@
+package pipe
+
+import (
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// FanOutStrategy specifies how a sender node distributes its output items across
+// its destination nodes.
+type FanOutStrategy int
+
+const (
+	// Broadcast sends each item to all the destination nodes. It is the default strategy.
+	Broadcast FanOutStrategy = iota
+	// RoundRobin sends each item to only one destination node, in turns.
+	RoundRobin
+	// LeastLoaded sends each item to only one destination node: the node with the shortest
+	// input queue that is ready to receive it, or the first node that becomes ready.
+	LeastLoaded
+	// keyHash sends each item to the destination node that is selected by the FanOutByKey function.
+	keyHash
+)
+
+// FanOut is an Option that specifies how a Start or Middle node distributes its output items
+// across its destination nodes. If it is passed to the NewBuilder function, it applies to all
+// the nodes in the pipeline. It does not have any effect on Final nodes.
+// The strategy applies to all the destinations of the node, including the bypassed nodes'
+// destinations and the destinations of feedback loops (see Sender.FeedbackTo).
+func FanOut(strategy FanOutStrategy) Option {
+	return func(options *creationOptions) {
+		options.fanOut = strategy
+		options.fanOutKey = nil
+	}
+}
+
+// FanOutByKey is an Option that makes a Start or Middle node to send each output item to only
+// one of its destination nodes, selected by the result of the hash function modulo the number
+// of destinations. This way, all the items with the same hash are sent to the same destination,
+// e.g. to shard the work across several identical nodes.
+// The OUT type must match the output type of the node. Otherwise, the Builder returns a
+// *ValidationError listing the node in its MismatchedFanOutKeys field.
+func FanOutByKey[OUT any](hash func(OUT) uint64) Option {
+	return func(options *creationOptions) {
+		options.fanOut = keyHash
+		options.fanOutKey = hash
+	}
+}
+
+// fanOut stores the FanOut options of a sender node
+type fanOut struct {
+	strategy FanOutStrategy
+	key      any
+}
+
+func fanOutOf(options *creationOptions) fanOut {
+	return fanOut{strategy: options.fanOut, key: options.fanOutKey}
+}
+
+// keyedSender nodes can distribute their output according to a FanOutByKey function
+type keyedSender interface {
+	// fanOutKeyMatches returns false if the FanOutByKey function of the node does not
+	// accept the type of its output
+	fanOutKeyMatches() bool
+}
+
+// keyMatches returns whether the FanOutByKey function, if any, accepts the OUT type
+func keyMatches[OUT any](fo fanOut) bool {
+	if fo.strategy != keyHash {
+		return true
+	}
+	_, ok := fo.key.(func(OUT) uint64)
+	return ok
+}
+
+func (sn *start[OUT]) fanOutKeyMatches() bool         { return keyMatches[OUT](sn.fanOut) }
+func (m *middle[IN, OUT]) fanOutKeyMatches() bool     { return keyMatches[OUT](m.fanOut) }
+func (mo *multiOutput[OUT]) fanOutKeyMatches() bool   { return keyMatches[OUT](mo.fanOut) }
+func (d *demuxOutput[OUT]) keyMatches(fo fanOut) bool { return keyMatches[OUT](fo) }
+func (sd *startDemux) fanOutKeyMatches() bool         { return sd.outputs.keyMatches(sd.fanOut) }
+func (m *middleDemux[IN]) fanOutKeyMatches() bool     { return m.outputs.keyMatches(m.fanOut) }
+
+func (o *demuxOutputs) keyMatches(fo fanOut) bool {
+	for _, name := range o.names {
+		if !o.byName[name].keyMatches(fo) {
+			return false
+		}
+	}
+	return true
+}
+
+// fork the output of a sender node to the provided joiners, according to the fan-out
+// strategy of the node
+func fork[OUT any](fo fanOut, joiners []*connect.Joiner[OUT]) (connect.Forker[OUT], error) {
+	switch fo.strategy {
+	case RoundRobin:
+		return connect.ForkRoundRobin(joiners...), nil
+	case LeastLoaded:
+		return connect.ForkLeastLoaded(joiners...), nil
+	case keyHash:
+		hash, ok := fo.key.(func(OUT) uint64)
+		if !ok {
+			// the Builder should have rejected it. Anyway, the destinations are connected, so they can finish
+			return connect.Discard(joiners...), fmt.Errorf(
+				"FanOutByKey expects a func(%s) uint64. Got %T", typeOf[OUT](), fo.key)
+		}
+		return connect.ForkByKey(hash, joiners...), nil
+	default:
+		return connect.Fork(joiners...), nil
+	}
+}
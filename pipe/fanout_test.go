@@ -0,0 +1,102 @@
+package pipe_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+type shardedPipe struct {
+	start  pipe.Start[int]
+	shard1 pipe.Final[int]
+	shard2 pipe.Final[int]
+	shard3 pipe.Final[int]
+}
+
+func (s *shardedPipe) Connect() {
+	s.start.SendTo(s.shard1, s.shard2, s.shard3)
+}
+
+// shardCollector collects the items that are received by each shard
+type shardCollector struct {
+	mt     sync.Mutex
+	shards map[int][]int
+	delay  map[int]time.Duration
+}
+
+func (sc *shardCollector) shard(n int) pipe.FinalFunc[int] {
+	return func(in <-chan int) {
+		for i := range in {
+			time.Sleep(sc.delay[n])
+			sc.mt.Lock()
+			sc.shards[n] = append(sc.shards[n], i)
+			sc.mt.Unlock()
+		}
+	}
+}
+
+func runSharded(t *testing.T, items int, delay map[int]time.Duration, opts ...pipe.Option) (*shardCollector, error) {
+	sc := &shardCollector{shards: map[int][]int{}, delay: delay}
+	p := pipe.NewBuilder(&shardedPipe{})
+	pipe.AddStart(p, func(s *shardedPipe) *pipe.Start[int] { return &s.start }, Counter(1, items), opts...)
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard1 }, sc.shard(1))
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard2 }, sc.shard(2))
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard3 }, sc.shard(3))
+	r, err := p.Build()
+	require.NoError(t, err)
+	r.Start()
+	return sc, r.Wait()
+}
+
+func TestFanOut_Broadcast(t *testing.T) {
+	sc, err := runSharded(t, 3, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]int{1: {1, 2, 3}, 2: {1, 2, 3}, 3: {1, 2, 3}}, sc.shards)
+}
+
+func TestFanOut_RoundRobin(t *testing.T) {
+	sc, err := runSharded(t, 9, nil, pipe.FanOut(pipe.RoundRobin))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]int{1: {1, 4, 7}, 2: {2, 5, 8}, 3: {3, 6, 9}}, sc.shards)
+}
+
+func TestFanOut_ByKey(t *testing.T) {
+	sc, err := runSharded(t, 9, nil, pipe.FanOutByKey(func(i int) uint64 {
+		return uint64(i % 3)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]int{1: {3, 6, 9}, 2: {1, 4, 7}, 3: {2, 5, 8}}, sc.shards)
+}
+
+func TestFanOut_ByKey_WrongType(t *testing.T) {
+	p := pipe.NewBuilder(&shardedPipe{})
+	pipe.AddStart(p, func(s *shardedPipe) *pipe.Start[int] { return &s.start }, Counter(1, 9),
+		pipe.FanOutByKey(func(s string) uint64 {
+			return uint64(len(s))
+		}))
+	sc := &shardCollector{shards: map[int][]int{}}
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard1 }, sc.shard(1))
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard2 }, sc.shard(2))
+	pipe.AddFinal(p, func(s *shardedPipe) *pipe.Final[int] { return &s.shard3 }, sc.shard(3))
+	_, err := p.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"start"}, verr.MismatchedFanOutKeys)
+	assert.Contains(t, err.Error(), "FanOutByKey functions not matching the output type [start]")
+}
+
+func TestFanOut_LeastLoaded(t *testing.T) {
+	sc, err := runSharded(t, 30, map[int]time.Duration{2: 20 * time.Millisecond},
+		pipe.FanOut(pipe.LeastLoaded))
+	require.NoError(t, err)
+	total := len(sc.shards[1]) + len(sc.shards[2]) + len(sc.shards[3])
+	assert.Equal(t, 30, total)
+	// the slow shard receives less items than the others
+	assert.Less(t, len(sc.shards[2]), len(sc.shards[1]))
+	assert.Less(t, len(sc.shards[2]), len(sc.shards[3]))
+}
@@ -0,0 +1,79 @@
+package pipe
+
+import "github.com/mariomac/pipes/pipe/internal/connect"
+
+// loopMember nodes can be part of a feedback loop
+type loopMember interface {
+	// setLoop sets the loop that counts the items in flight in the loop of the node. It
+	// must be invoked before the node starts.
+	setLoop(l *connect.Loop)
+}
+
+// setLoops finds the feedback loops of the pipeline, which are formed by the nodes that can
+// reach each other through the SendTo and FeedbackTo connections, and sets a connect.Loop
+// for each of them. This way, the destinations of the FeedbackTo connections can close their
+// input once their non-feedback senders have finished, and there are no items in flight in the
+// loop.
+func setLoops(nodes []pipeNode) {
+	// Tarjan's algorithm for strongly connected components
+	index := map[pipeNode]int{}
+	lowLink := map[pipeNode]int{}
+	onStack := map[pipeNode]bool{}
+	var stack []pipeNode
+	var visit func(n pipeNode)
+	visit = func(n pipeNode) {
+		index[n] = len(index)
+		lowLink[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, dst := range append(n.destinations(), n.feedbackDestinations()...) {
+			if dst == nil || dst.isNil() {
+				continue
+			}
+			if _, ok := index[dst]; !ok {
+				visit(dst)
+				if lowLink[dst] < lowLink[n] {
+					lowLink[n] = lowLink[dst]
+				}
+			} else if onStack[dst] && index[dst] < lowLink[n] {
+				lowLink[n] = index[dst]
+			}
+		}
+		if lowLink[n] != index[n] {
+			return
+		}
+		var component []pipeNode
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == n {
+				break
+			}
+		}
+		if len(component) == 1 && !feedsItself(n) {
+			return
+		}
+		loop := connect.NewLoop()
+		for _, member := range component {
+			if lm, ok := member.(loopMember); ok {
+				lm.setLoop(loop)
+			}
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := index[n]; !ok && !n.isNil() {
+			visit(n)
+		}
+	}
+}
+
+func feedsItself(n pipeNode) bool {
+	for _, dst := range n.feedbackDestinations() {
+		if dst == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package pipe_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+type feedbackPipe struct {
+	start  pipe.Start[int]
+	inc    pipe.Middle[int, int]
+	router pipe.MiddleDemux[int]
+	final  pipe.Final[int]
+}
+
+func (n *feedbackPipe) Connect() {
+	n.start.SendTo(n.inc)
+	n.inc.SendTo(n.router)
+	pipe.DemuxAdd[int](n.router, "big").SendTo(n.final)
+	// small numbers are sent back to the incrementer until they are big enough
+	pipe.DemuxAdd[int](n.router, "small").FeedbackTo(n.inc)
+}
+
+func TestFeedback(t *testing.T) {
+	b := pipe.NewBuilder(&feedbackPipe{})
+	pipe.AddStart(b, func(n *feedbackPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	pipe.AddMiddle(b, func(n *feedbackPipe) *pipe.Middle[int, int] { return &n.inc },
+		func(in <-chan int, out chan<- int) {
+			for i := range in {
+				out <- i + 3
+			}
+		})
+	pipe.AddMiddleDemux(b, func(n *feedbackPipe) *pipe.MiddleDemux[int] { return &n.router },
+		func(in <-chan int, out pipe.Demux) {
+			big, small := pipe.DemuxGet[int](out, "big"), pipe.DemuxGet[int](out, "small")
+			for i := range in {
+				if i == 11 {
+					// the loop is not closed while any of its nodes is processing an item
+					time.Sleep(200 * time.Millisecond)
+				}
+				if i >= 10 {
+					big <- i
+				} else {
+					small <- i
+				}
+			}
+		})
+	var collected []int
+	pipe.AddFinal(b, func(n *feedbackPipe) *pipe.Final[int] { return &n.final }, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := b.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+
+	sort.Ints(collected)
+	// 1 -> 4 -> 7 -> 10; 2 -> 5 -> 8 -> 11; 3 -> 6 -> 9 -> 12
+	assert.Equal(t, []int{10, 11, 12}, collected)
+}
+
+func TestFeedback_Panic(t *testing.T) {
+	b := pipe.NewBuilder(&feedbackPipe{})
+	pipe.AddStart(b, func(n *feedbackPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	// the item that makes the node panic is never consumed, so the loop would never be drained
+	pipe.AddMiddle(b, func(n *feedbackPipe) *pipe.Middle[int, int] { return &n.inc },
+		func(in <-chan int, out chan<- int) {
+			for i := range in {
+				if i == 5 {
+					panic("boom")
+				}
+				out <- i + 3
+			}
+		}, pipe.OnPanic(pipe.PanicRestartNode))
+	pipe.AddMiddleDemux(b, func(n *feedbackPipe) *pipe.MiddleDemux[int] { return &n.router },
+		func(in <-chan int, out pipe.Demux) {
+			big, small := pipe.DemuxGet[int](out, "big"), pipe.DemuxGet[int](out, "small")
+			for i := range in {
+				if i >= 10 {
+					big <- i
+				} else {
+					small <- i
+				}
+			}
+		})
+	pipe.AddFinal(b, func(n *feedbackPipe) *pipe.Final[int] { return &n.final }, func(in <-chan int) {
+		for range in {
+		}
+	})
+	r, err := b.Build()
+	require.NoError(t, err)
+
+	// the panic aborts the loop, so the pipeline finishes anyway
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	var perr *pipe.PanicError
+	require.ErrorAs(t, r.Err(), &perr)
+}
@@ -0,0 +1,221 @@
+package pipe
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NodeKind specifies the role of a node in the pipeline.
+type NodeKind int
+
+const (
+	// StartKind identifies Start nodes
+	StartKind NodeKind = iota
+	// MiddleKind identifies Middle nodes, including the bypassed ones
+	MiddleKind
+	// FinalKind identifies Final nodes
+	FinalKind
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case StartKind:
+		return "Start"
+	case MiddleKind:
+		return "Middle"
+	case FinalKind:
+		return "Final"
+	default:
+		return fmt.Sprintf("NodeKind(%d)", int(k))
+	}
+}
+
+// NodeInfo describes a node of the pipeline.
+type NodeInfo struct {
+	// Name of the node. By default, it is the name of the NodesMap field where the node is stored.
+	Name string
+	Kind NodeKind
+	// In is the type of the input channel of the node. It is nil for Start nodes.
+	// For multi-input nodes (e.g. Middle2 or Final2), it is the type of the first input.
+	In reflect.Type
+	// Inputs are the types of the input channels of multi-input nodes, in order.
+	// It is nil for the rest of nodes.
+	Inputs []reflect.Type
+	// Out is the type of the output channel of the node. It is nil for Final nodes and
+	// for demux nodes (see StartDemux and MiddleDemux), whose outputs are described in their Edges.
+	Out reflect.Type
+	// ChannelBufferLen is the length of the input channel of the node.
+	ChannelBufferLen int
+	// Bypassed is true for the Middle nodes that have been bypassed because their provider
+	// didn't return any function (see Bypass).
+	Bypassed bool
+}
+
+// Edge describes a connection between two nodes of the pipeline.
+type Edge struct {
+	// From is the name of the sender node
+	From string
+	// To is the name of the receiver node
+	To string
+	// Feedback is true if the connection has been defined by the Sender.FeedbackTo method.
+	Feedback bool
+	// Type of the data that is sent through the connection.
+	Type reflect.Type
+	// Output is the name of the output of the sender node, for demux nodes (see DemuxAdd).
+	// It is empty otherwise.
+	Output string
+}
+
+// Graph is a read-only description of the nodes of a pipeline and their connections.
+// The nodes that have been ignored (e.g. because their provider returned IgnoreStart or
+// IgnoreFinal) are not part of the Graph.
+type Graph struct {
+	// Nodes are sorted in the same order as the fields of the NodesMap.
+	Nodes []NodeInfo
+	Edges []Edge
+}
+
+// Graph returns the description of the nodes of the pipeline and their connections.
+func (b *Runner) Graph() Graph {
+	g := Graph{}
+	b.nodesMt.RLock()
+	defer b.nodesMt.RUnlock()
+	for _, n := range b.nodes {
+		if n.isNil() {
+			continue
+		}
+		g.Nodes = append(g.Nodes, n.describe())
+		if dm, ok := n.(Demuxer); ok {
+			outs := dm.demuxOutputs()
+			for _, output := range outs.names {
+				g.appendEdges(n, output, outs.byName[output].outType(), outs.byName[output])
+			}
+		} else {
+			g.appendEdges(n, "", n.describe().Out, n)
+		}
+	}
+	return g
+}
+
+// appendEdges from the sender node, whose destinations are provided by the output argument
+func (g *Graph) appendEdges(sender pipeNode, output string, typ reflect.Type, dsts interface {
+	destinations() []pipeNode
+	feedbackDestinations() []pipeNode
+}) {
+	for _, dst := range dsts.destinations() {
+		if dst != nil && !dst.isNil() {
+			g.Edges = append(g.Edges, Edge{
+				From: sender.nodeName(), To: dst.nodeName(), Type: typ, Output: output,
+			})
+		}
+	}
+	for _, dst := range dsts.feedbackDestinations() {
+		if dst != nil && !dst.isNil() {
+			g.Edges = append(g.Edges, Edge{
+				From: sender.nodeName(), To: dst.nodeName(), Type: typ, Output: output, Feedback: true,
+			})
+		}
+	}
+}
+
+func (e *Edge) label() string {
+	if e.Output != "" {
+		return e.Output + ": " + e.Type.String()
+	}
+	return e.Type.String()
+}
+
+// Mermaid returns the Graph as a Mermaid flowchart (https://mermaid.js.org/syntax/flowchart.html).
+// Start nodes are drawn as stadiums, Final nodes as cylinders and bypassed nodes have a dashed border.
+// Edges are labeled with the type of the data they carry (prefixed by the output name, for
+// demux nodes), and feedback edges are dotted.
+// The nodes are identified as n0, n1... in the order of the Nodes slice, and their names are
+// only written in their labels, so they can contain any character.
+func (g Graph) Mermaid() string {
+	sb := strings.Builder{}
+	sb.WriteString("flowchart TD\n")
+	ids := make(map[string]string, len(g.Nodes))
+	var bypassed []string
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.Name] = id
+		label := mermaidEscape(n.label())
+		switch n.Kind {
+		case StartKind:
+			fmt.Fprintf(&sb, "    %s([\"%s\"])\n", id, label)
+		case FinalKind:
+			fmt.Fprintf(&sb, "    %s[(\"%s\")]\n", id, label)
+		default:
+			fmt.Fprintf(&sb, "    %s[\"%s\"]\n", id, label)
+		}
+		if n.Bypassed {
+			bypassed = append(bypassed, id)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Feedback {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&sb, "    %s %s|\"%s\"| %s\n",
+			ids[e.From], arrow, mermaidEscape(e.label()), ids[e.To])
+	}
+	if len(bypassed) > 0 {
+		sb.WriteString("    classDef bypassed stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&sb, "    class %s bypassed\n", strings.Join(bypassed, ","))
+	}
+	return sb.String()
+}
+
+// DOT returns the Graph in the Graphviz DOT language (https://graphviz.org/doc/info/lang.html).
+// Start nodes are drawn as ellipses, Middle nodes as boxes and Final nodes as cylinders.
+// Edges are labeled with the type of the data they carry (prefixed by the output name, for
+// demux nodes). Bypassed nodes and feedback edges are dashed.
+func (g Graph) DOT() string {
+	sb := strings.Builder{}
+	sb.WriteString("digraph pipeline {\n")
+	for _, n := range g.Nodes {
+		shape := "box"
+		switch n.Kind {
+		case StartKind:
+			shape = "ellipse"
+		case FinalKind:
+			shape = "cylinder"
+		}
+		style := ""
+		if n.Bypassed {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&sb, "    %q [label=%q, shape=%s%s];\n", n.Name, n.label(), shape, style)
+	}
+	for _, e := range g.Edges {
+		style := ""
+		if e.Feedback {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&sb, "    %q -> %q [label=%q%s];\n", e.From, e.To, e.label(), style)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (n *NodeInfo) label() string {
+	if n.ChannelBufferLen > 0 {
+		return fmt.Sprintf("%s\nbuffer: %d", n.Name, n.ChannelBufferLen)
+	}
+	return n.Name
+}
+
+// mermaidEscape replaces the characters that would break a Mermaid quoted text
+// by their HTML entity codes.
+func mermaidEscape(text string) string {
+	return strings.NewReplacer(
+		`"`, "#quot;",
+		"\n", "<br/>",
+	).Replace(text)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
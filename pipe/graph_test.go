@@ -0,0 +1,131 @@
+package pipe_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+type graphPipe struct {
+	start   pipe.Start[int]
+	ignored pipe.Start[int]
+	toStr   pipe.Middle[int, string]
+	skip    pipe.Middle[string, string]
+	retry   pipe.Middle[string, string]
+	print   pipe.Final[string]
+}
+
+func (n *graphPipe) Connect() {
+	n.start.SendTo(n.toStr)
+	n.ignored.SendTo(n.toStr)
+	n.toStr.SendTo(n.skip, n.retry)
+	n.skip.SendTo(n.print)
+	n.retry.FeedbackTo(n.skip)
+}
+
+func buildGraphPipe(t *testing.T) *pipe.Runner {
+	b := pipe.NewBuilder(&graphPipe{})
+	pipe.AddStart(b, func(n *graphPipe) *pipe.Start[int] { return &n.start }, Counter(1, 3))
+	pipe.AddStartProvider(b, func(n *graphPipe) *pipe.Start[int] { return &n.ignored },
+		func() (pipe.StartFunc[int], error) {
+			return pipe.IgnoreStart[int](), nil
+		})
+	pipe.AddMiddle(b, func(n *graphPipe) *pipe.Middle[int, string] { return &n.toStr },
+		func(in <-chan int, out chan<- string) {
+			for i := range in {
+				out <- strconv.Itoa(i)
+			}
+		}, pipe.ChannelBufferLen(10))
+	pipe.AddMiddleProvider(b, func(n *graphPipe) *pipe.Middle[string, string] { return &n.skip },
+		func() (pipe.MiddleFunc[string, string], error) {
+			return pipe.Bypass[string](), nil
+		})
+	pipe.AddMiddle(b, func(n *graphPipe) *pipe.Middle[string, string] { return &n.retry },
+		func(in <-chan string, _ chan<- string) {
+			for range in {
+			}
+		})
+	pipe.AddFinal(b, func(n *graphPipe) *pipe.Final[string] { return &n.print },
+		func(in <-chan string) {
+			for range in {
+			}
+		})
+	r, err := b.Build()
+	require.NoError(t, err)
+	return r
+}
+
+func TestGraph(t *testing.T) {
+	g := buildGraphPipe(t)
+	intType, strType := reflect.TypeOf(0), reflect.TypeOf("")
+
+	graph := g.Graph()
+	assert.Equal(t, []pipe.NodeInfo{
+		{Name: "start", Kind: pipe.StartKind, Out: intType},
+		{Name: "toStr", Kind: pipe.MiddleKind, In: intType, Out: strType, ChannelBufferLen: 10},
+		{Name: "skip", Kind: pipe.MiddleKind, In: strType, Out: strType, Bypassed: true},
+		{Name: "retry", Kind: pipe.MiddleKind, In: strType, Out: strType},
+		{Name: "print", Kind: pipe.FinalKind, In: strType},
+	}, graph.Nodes)
+	assert.Equal(t, []pipe.Edge{
+		{From: "start", To: "toStr", Type: intType},
+		{From: "toStr", To: "skip", Type: strType},
+		{From: "toStr", To: "retry", Type: strType},
+		{From: "skip", To: "print", Type: strType},
+		{From: "retry", To: "skip", Type: strType, Feedback: true},
+	}, graph.Edges)
+}
+
+func TestGraph_Mermaid(t *testing.T) {
+	assert.Equal(t, `flowchart TD
+    n0(["start"])
+    n1["toStr<br/>buffer: 10"]
+    n2["skip"]
+    n3["retry"]
+    n4[("print")]
+    n0 -->|"int"| n1
+    n1 -->|"string"| n2
+    n1 -->|"string"| n3
+    n2 -->|"string"| n4
+    n3 -.->|"string"| n2
+    classDef bypassed stroke-dasharray: 5 5
+    class n2 bypassed
+`, buildGraphPipe(t).Graph().Mermaid())
+}
+
+func TestGraph_Mermaid_UnsafeNames(t *testing.T) {
+	intType := reflect.TypeOf(0)
+	g := pipe.Graph{
+		Nodes: []pipe.NodeInfo{
+			{Name: "my source", Kind: pipe.StartKind, Out: intType},
+			{Name: "end", Kind: pipe.FinalKind, In: intType},
+		},
+		Edges: []pipe.Edge{{From: "my source", To: "end", Type: intType}},
+	}
+	assert.Equal(t, `flowchart TD
+    n0(["my source"])
+    n1[("end")]
+    n0 -->|"int"| n1
+`, g.Mermaid())
+}
+
+func TestGraph_DOT(t *testing.T) {
+	assert.Equal(t, `digraph pipeline {
+    "start" [label="start", shape=ellipse];
+    "toStr" [label="toStr\nbuffer: 10", shape=box];
+    "skip" [label="skip", shape=box, style=dashed];
+    "retry" [label="retry", shape=box];
+    "print" [label="print", shape=cylinder];
+    "start" -> "toStr" [label="int"];
+    "toStr" -> "skip" [label="string"];
+    "toStr" -> "retry" [label="string"];
+    "skip" -> "print" [label="string"];
+    "retry" -> "skip" [label="string", style=dashed];
+}
+`, buildGraphPipe(t).Graph().DOT())
+}
@@ -9,6 +9,22 @@ type Joiner[IN any] struct {
 	totalSenders int32
 	bufLen       int
 	channel      chan IN
+
+	// feedback is only set when this Joiner receives data from a feedback loop
+	feedback *Joiner[IN]
+	// forwardDone is closed when all the non-feedback senders have released the Joiner
+	forwardDone chan struct{}
+	// loop is only set when this Joiner is the input of a node of a feedback loop
+	loop *Loop
+
+	overflow OverflowPolicy
+	spill    func(IN)
+	dropped  uint64
+
+	// handoffs is the number of edges that hold an item while it waits for room in the channel
+	// (see newEdges), and handedOff the number of items that they are holding
+	handoffs  int32
+	handedOff int32
 }
 
 // NewJoiner creates a joiner for a given channel type and buffer length
@@ -24,6 +40,18 @@ func (j *Joiner[IN]) Receiver() chan IN {
 	return j.channel
 }
 
+// BufferLen returns the length of the buffer of the channel, plus the item that each
+// blocking edge can hold while it waits for room in the channel
+func (j *Joiner[IN]) BufferLen() int {
+	return j.bufLen + int(atomic.LoadInt32(&j.handoffs))
+}
+
+// Len returns the number of items that are queued in the buffer of the channel, plus the
+// items that are held by the blocking edges
+func (j *Joiner[IN]) Len() int {
+	return len(j.channel) + int(atomic.LoadInt32(&j.handedOff))
+}
+
 // AcquireSender gets acces to the channel as a sender. The acquirer must finally invoke
 // ReleaseSender to make sure that the channel is closed when all the senders released it.
 func (j *Joiner[IN]) AcquireSender() chan IN {
@@ -31,12 +59,100 @@ func (j *Joiner[IN]) AcquireSender() chan IN {
 	return j.channel
 }
 
+// TryAcquireSender acquires the channel as AcquireSender does, unless all the previous
+// senders have already released it. In that case, it returns false and the channel
+// can't be used anymore.
+func (j *Joiner[IN]) TryAcquireSender() (chan IN, bool) {
+	for {
+		senders := atomic.LoadInt32(&j.totalSenders)
+		if senders == 0 {
+			return nil, false
+		}
+		if atomic.CompareAndSwapInt32(&j.totalSenders, senders, senders+1) {
+			return j.channel, true
+		}
+	}
+}
+
 // ReleaseSender will close the channel when all the invokers of the AcquireSender have invoked
 // this function
 func (j *Joiner[IN]) ReleaseSender() {
 	// if no senders, we close the main channel
 	if atomic.AddInt32(&j.totalSenders, -1) == 0 {
-		close(j.channel)
+		if j.feedback != nil {
+			// the channel will be closed once the feedback loop is drained
+			close(j.forwardDone)
+		} else {
+			close(j.channel)
+		}
+	}
+}
+
+// Feedback returns a Joiner that allows sending data back to this Joiner from nodes that
+// are downstream of it, forming a feedback loop. The data received from the feedback
+// Joiner is queued without limit, to avoid that the nodes of the loop block each other.
+// Once all the senders of this Joiner have released it, the channel is closed when the
+// Loop of the Joiner has no items in flight (see SetLoop), or when all the senders of the
+// feedback Joiner have released it, if this Joiner does not belong to any Loop.
+// Feedback must be invoked before any sender invokes ReleaseSender. Successive invocations
+// return the same feedback Joiner.
+func (j *Joiner[IN]) Feedback() *Joiner[IN] {
+	if j.feedback == nil {
+		fb := NewJoiner[IN](j.bufLen)
+		fb.loop = j.loop
+		j.feedback = &fb
+		j.forwardDone = make(chan struct{})
+		go j.mergeFeedback()
+	}
+	return j.feedback
+}
+
+func (j *Joiner[IN]) mergeFeedback() {
+	var pending []IN
+	feedback, forwardDone := j.feedback.channel, j.forwardDone
+	// empty is only watched after all the forward senders released the joiner
+	var empty <-chan struct{}
+	for {
+		if forwardDone == nil {
+			drained := feedback == nil
+			if j.loop != nil {
+				drained, empty = j.loop.drained()
+			}
+			if drained {
+				// if the loop has been aborted, the items in flight are discarded
+				close(j.channel)
+				if feedback != nil {
+					go discard(feedback)
+				}
+				return
+			}
+		}
+		var sendCh chan IN
+		var next IN
+		if len(pending) > 0 {
+			sendCh, next = j.channel, pending[0]
+		}
+		select {
+		case item, ok := <-feedback:
+			if !ok {
+				feedback = nil
+				continue
+			}
+			pending = append(pending, item)
+		case sendCh <- next:
+			var zero IN
+			pending[0] = zero
+			pending = pending[1:]
+		case <-forwardDone:
+			forwardDone = nil
+		case <-empty:
+		}
+	}
+}
+
+func discard[T any](ch <-chan T) {
+	//nolint:revive
+	for range ch {
 	}
 }
 
@@ -50,16 +166,18 @@ type Forker[OUT any] struct {
 	totalSenders   int32
 	sendCh         chan OUT
 	releaseChannel Releaser
+	// source is nil if the Forker sends the data directly to the channel of its joiner
+	source *source
 }
 
 // Fork provides connection to a group of output Nodes, accessible through their respective
-// Joiner instances.
+// Joiner instances. Each item is sent to all the joiners.
 func Fork[T any](joiners ...*Joiner[T]) Forker[T] {
 	if len(joiners) == 0 {
 		panic("can't fork 0 joiners")
 	}
 	// if there is only one joiner, we directly send the data to the channel, without intermediation
-	if len(joiners) == 1 {
+	if len(joiners) == 1 && joiners[0].overflow == Block && joiners[0].loop == nil {
 		return Forker[T]{
 			sendCh:         joiners[0].AcquireSender(),
 			releaseChannel: joiners[0].ReleaseSender,
@@ -68,24 +186,22 @@ func Fork[T any](joiners ...*Joiner[T]) Forker[T] {
 	// channel used as input from the source Node
 	sendCh := make(chan T, joiners[0].bufLen)
 
-	// channels that clone the contents of the sendCh
-	forwarders := make([]chan T, len(joiners))
-	for i := 0; i < len(joiners); i++ {
-		forwarders[i] = joiners[i].AcquireSender()
-	}
+	// edges that clone the contents of the sendCh
+	edges := newEdges(joiners)
+	src := &source{}
 	go func() {
 		for in := range sendCh {
-			for i := 0; i < len(joiners); i++ {
-				forwarders[i] <- in
+			for i := range edges {
+				edges[i].send(in)
 			}
+			src.consumed()
 		}
-		for i := 0; i < len(joiners); i++ {
-			joiners[i].ReleaseSender()
-		}
+		releaseEdges(edges)
 	}()
 	return Forker[T]{
 		sendCh:         sendCh,
 		releaseChannel: func() { close(sendCh) },
+		source:         src,
 	}
 }
 
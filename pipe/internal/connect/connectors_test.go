@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	helpers "github.com/mariomac/pipes/testers"
 )
@@ -99,3 +100,192 @@ func TestForker(t *testing.T) {
 		close(r)
 	})
 }
+
+func TestJoiner_Feedback(t *testing.T) {
+	loop := NewLoop()
+	j := NewJoiner[int](0)
+	j.SetLoop(loop)
+	fb := j.Feedback()
+	// the feedback joiner is the same for successive invocations
+	assert.Same(t, fb, j.Feedback())
+
+	// the node of the loop sends each received value back to the joiner until it is
+	// higher than 5. Then it sends it out of the loop
+	results := NewJoiner[int](10)
+	back, out := Fork(fb), Fork(&results)
+	back.SetLoop(loop)
+	out.SetLoop(loop)
+	go func() {
+		backCh, outCh := back.AcquireSender(), out.AcquireSender()
+		for i := range j.Receiver() {
+			// the joiner is not closed while the node is slowly processing the loop
+			time.Sleep(20 * time.Millisecond)
+			if i < 5 {
+				backCh <- i + 3
+			} else {
+				outCh <- i
+			}
+		}
+		back.ReleaseSender()
+		out.ReleaseSender()
+	}()
+
+	// the items that are sent to the loop are counted by the Forker of the sender
+	forward := Fork(&j)
+	sender := forward.AcquireSender()
+	sender <- 1
+	sender <- 2
+	forward.ReleaseSender()
+
+	var received []int
+	finished := helpers.AsyncWait(1)
+	go func() {
+		for i := range results.Receiver() {
+			received = append(received, i)
+		}
+		finished.Done()
+	}()
+	finished.Wait(t, timeout)
+	assert.ElementsMatch(t, []int{5, 7}, received)
+	assert.Zero(t, loop.InFlight())
+}
+
+func TestJoiner_Feedback_Abort(t *testing.T) {
+	loop := NewLoop()
+	j := NewJoiner[int](0)
+	j.SetLoop(loop)
+	back := Fork(j.Feedback())
+	back.SetLoop(loop)
+	back.AcquireSender()
+	defer back.ReleaseSender()
+
+	forward := Fork(&j)
+	forward.AcquireSender() <- 1
+	forward.ReleaseSender()
+
+	// the node of the loop returns without consuming the item
+	<-j.Receiver()
+	assert.EqualValues(t, 1, loop.InFlight())
+	loop.Abort()
+
+	finished := helpers.AsyncWait(1)
+	go func() {
+		for range j.Receiver() {
+		}
+		finished.Done()
+	}()
+	finished.Wait(t, timeout)
+}
+
+func TestInstrumentedConnectors(t *testing.T) {
+	stats := Stats{}
+	joiner := NewJoiner[int](0)
+	forker := Instrument(&stats, Fork(&joiner))
+	received := joiner.InstrumentedReceiver(&stats)
+	go func() {
+		out := forker.AcquireSender()
+		for i := 1; i <= 3; i++ {
+			out <- i
+		}
+		forker.ReleaseSender()
+	}()
+	var items []int
+	for i := range received {
+		time.Sleep(time.Millisecond)
+		items = append(items, i)
+	}
+	assert.Equal(t, []int{1, 2, 3}, items)
+	assert.Equal(t, uint64(3), stats.ItemsIn)
+	assert.Equal(t, uint64(3), stats.ItemsOut)
+	assert.NotZero(t, stats.BlockedNanos)
+	assert.NotZero(t, stats.ProcessingSamples)
+	assert.GreaterOrEqual(t, stats.ProcessingNanos, int64(stats.ProcessingSamples)*int64(time.Millisecond))
+}
+
+func TestForker_SlowReceiver(t *testing.T) {
+	fast := NewJoiner[int](2)
+	slow := NewJoiner[int](2)
+	f := Fork(&fast, &slow)
+	go func() {
+		sender := f.AcquireSender()
+		for i := 1; i <= 5; i++ {
+			sender <- i
+		}
+		f.ReleaseSender()
+	}()
+	// the slow receiver isn't reading, but the fast receiver gets the items until the
+	// buffer of the slow receiver is full and its edge holds the next item
+	fastCh := fast.Receiver()
+	for i := 1; i <= 4; i++ {
+		assert.Equal(t, i, helpers.ReadChannel(t, fastCh, timeout))
+	}
+	// the item that is held by the edge is reported as queued
+	assert.Eventually(t, func() bool { return slow.Len() == 3 }, timeout, time.Millisecond)
+	assert.Equal(t, 3, slow.BufferLen())
+	select {
+	case i := <-fastCh:
+		assert.Failf(t, "the fast receiver shouldn't receive more items", "received %d", i)
+	case <-time.After(10 * time.Millisecond):
+	}
+	slowCh := slow.Receiver()
+	for i := 1; i <= 5; i++ {
+		assert.Equal(t, i, helpers.ReadChannel(t, slowCh, timeout))
+	}
+	assert.Equal(t, 5, helpers.ReadChannel(t, fastCh, timeout))
+}
+
+func TestSwitch(t *testing.T) {
+	joiner1 := NewJoiner[int](20)
+	joiner2 := NewJoiner[int](20)
+
+	sw, f := NewSwitch(Fork(&joiner1))
+	sender := f.AcquireSender()
+	sender <- 1
+	assert.Equal(t, 1, helpers.ReadChannel(t, joiner1.Receiver(), timeout))
+
+	// the previous joiner is released when the switch is replaced
+	assert.True(t, sw.Replace(Fork(&joiner2)))
+	_, ok := <-joiner1.Receiver()
+	assert.False(t, ok)
+
+	sender <- 2
+	f.ReleaseSender()
+	assert.Equal(t, 2, helpers.ReadChannel(t, joiner2.Receiver(), timeout))
+	_, ok = <-joiner2.Receiver()
+	assert.False(t, ok)
+
+	// a released switch can't be replaced
+	joiner3 := NewJoiner[int](20)
+	assert.False(t, sw.Replace(Fork(&joiner3)))
+}
+
+func TestSwitch_TapsWithStalledDownstream(t *testing.T) {
+	downstream := NewJoiner[int](0)
+	tap1 := NewJoiner[int](10)
+	tap1.SetOverflow(DropNewest, nil)
+	tap2 := NewJoiner[int](10)
+	tap2.SetOverflow(DropNewest, nil)
+
+	sw, f := NewSwitch(Fork(&downstream))
+	require.True(t, sw.AddTap(&tap1))
+	sender := f.AcquireSender()
+	// nobody reads from the downstream joiner, so the switch is blocked sending this item
+	sender <- 1
+
+	// taps can be attached and detached while the main downstream is stalled
+	attached := make(chan bool)
+	go func() {
+		sw.RemoveTap(&tap1)
+		attached <- sw.AddTap(&tap2)
+	}()
+	assert.True(t, helpers.ReadChannel(t, attached, timeout))
+	_, ok := <-tap1.Receiver()
+	assert.False(t, ok)
+
+	// once the main downstream is unblocked, the attached tap receives a copy of the item
+	assert.Equal(t, 1, helpers.ReadChannel(t, downstream.Receiver(), timeout))
+	assert.Equal(t, 1, helpers.ReadChannel(t, tap2.Receiver(), timeout))
+	f.ReleaseSender()
+	_, ok = <-tap2.Receiver()
+	assert.False(t, ok)
+}
@@ -0,0 +1,167 @@
+package connect
+
+import (
+	"reflect"
+)
+
+// ForkRoundRobin provides connection to a group of output Nodes, accessible through their
+// respective Joiner instances. Instead of sending each item to all the joiners, as Fork does,
+// each item is sent to only one joiner, in turns.
+func ForkRoundRobin[T any](joiners ...*Joiner[T]) Forker[T] {
+	next := 0
+	return distribute(func(_ T) int {
+		dst := next
+		next = (next + 1) % len(joiners)
+		return dst
+	}, joiners)
+}
+
+// ForkByKey provides connection to a group of output Nodes, accessible through their
+// respective Joiner instances. Each item is sent to only one joiner, whose index is the
+// result of the hash function for that item, modulo the number of joiners. This way, all
+// the items with the same hash are sent to the same joiner.
+func ForkByKey[T any](hash func(T) uint64, joiners ...*Joiner[T]) Forker[T] {
+	n := uint64(len(joiners))
+	return distribute(func(item T) int {
+		return int(hash(item) % n)
+	}, joiners)
+}
+
+// distribute sends each item to the joiner whose index is returned by the dst function
+func distribute[T any](dst func(T) int, joiners []*Joiner[T]) Forker[T] {
+	if len(joiners) == 0 {
+		panic("can't fork 0 joiners")
+	}
+	if len(joiners) == 1 {
+		return Fork(joiners...)
+	}
+	sendCh := make(chan T, joiners[0].bufLen)
+	edges := newEdges(joiners)
+	src := &source{}
+	go func() {
+		for in := range sendCh {
+			edges[dst(in)].send(in)
+			src.consumed()
+		}
+		releaseEdges(edges)
+	}()
+	return Forker[T]{
+		sendCh:         sendCh,
+		releaseChannel: func() { close(sendCh) },
+		source:         src,
+	}
+}
+
+// Discard provides connection to a group of output Nodes, accessible through their respective
+// Joiner instances, without sending them any data. All the sent items are discarded, and the
+// joiners are released when the Forker is released.
+func Discard[T any](joiners ...*Joiner[T]) Forker[T] {
+	sendCh := make(chan T)
+	acquireAll(joiners)
+	src := &source{}
+	go func() {
+		for range sendCh {
+			src.consumed()
+		}
+		releaseAll(joiners)
+	}()
+	return Forker[T]{
+		sendCh:         sendCh,
+		releaseChannel: func() { close(sendCh) },
+		source:         src,
+	}
+}
+
+// ForkLeastLoaded provides connection to a group of output Nodes, accessible through their
+// respective Joiner instances. Each item is sent to only one joiner: the joiner with the
+// shortest queue that is ready to receive it. If no joiner is ready, the item is sent to
+// the first joiner that becomes ready. The joiners with the same queue length are tried in turns,
+// so the items are distributed across unbuffered joiners that are equally ready.
+// The OverflowPolicy of the joiners is ignored, as the item is never sent to a full joiner.
+func ForkLeastLoaded[T any](joiners ...*Joiner[T]) Forker[T] {
+	if len(joiners) == 0 {
+		panic("can't fork 0 joiners")
+	}
+	if len(joiners) == 1 {
+		return Fork(joiners...)
+	}
+	sendCh := make(chan T, joiners[0].bufLen)
+	forwarders := acquireAll(joiners)
+	cases := make([]reflect.SelectCase, len(forwarders))
+	for i, fw := range forwarders {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(fw)}
+	}
+	src := &source{}
+	go func() {
+		offset := 0
+		candidates := make([]int, len(forwarders))
+		for in := range sendCh {
+			sortByLoad(candidates, forwarders, offset)
+			offset = (offset + 1) % len(forwarders)
+			if !trySend(in, candidates, joiners, forwarders) {
+				// all the destinations are busy. Waiting for the first one to be ready
+				value := reflect.ValueOf(&in).Elem()
+				for i := range cases {
+					cases[i].Send = value
+					joiners[i].enter()
+				}
+				chosen, _, _ := reflect.Select(cases)
+				for i := range joiners {
+					if i != chosen {
+						joiners[i].leave()
+					}
+				}
+			}
+			src.consumed()
+		}
+		releaseAll(joiners)
+	}()
+	return Forker[T]{
+		sendCh:         sendCh,
+		releaseChannel: func() { close(sendCh) },
+		source:         src,
+	}
+}
+
+// sortByLoad stores in candidates the indices of the forwarders, sorted by queue length.
+// The forwarders with the same length are sorted from the provided offset
+func sortByLoad[T any](candidates []int, forwarders []chan T, offset int) {
+	for i := range candidates {
+		candidates[i] = (i + offset) % len(candidates)
+	}
+	// insertion sort, as we expect few destinations
+	for i := 1; i < len(candidates); i++ {
+		for j := i; j > 0 && len(forwarders[candidates[j]]) < len(forwarders[candidates[j-1]]); j-- {
+			candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+		}
+	}
+}
+
+// trySend sends the item to the first candidate that is ready to receive it, without blocking
+func trySend[T any](item T, candidates []int, joiners []*Joiner[T], forwarders []chan T) bool {
+	for _, c := range candidates {
+		// the item is counted before sending it, as it could be consumed right after
+		joiners[c].enter()
+		select {
+		case forwarders[c] <- item:
+			return true
+		default:
+			joiners[c].leave()
+		}
+	}
+	return false
+}
+
+func acquireAll[T any](joiners []*Joiner[T]) []chan T {
+	forwarders := make([]chan T, len(joiners))
+	for i := range joiners {
+		forwarders[i] = joiners[i].AcquireSender()
+	}
+	return forwarders
+}
+
+func releaseAll[T any](joiners []*Joiner[T]) {
+	for i := range joiners {
+		joiners[i].ReleaseSender()
+	}
+}
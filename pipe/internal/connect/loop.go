@@ -0,0 +1,126 @@
+package connect
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Loop counts the items that are in flight in a feedback loop: the items that have been sent to
+// the Joiners of the nodes of the loop (see Joiner.SetLoop) and haven't been consumed yet. An item
+// is consumed when the node that received it sends its result through a Forker of the loop (see
+// Forker.SetLoop), or when it is discarded by the OverflowPolicy of its Joiner.
+type Loop struct {
+	inFlight int64
+
+	mt      sync.Mutex
+	aborted bool
+	// empty is closed and replaced each time the loop becomes empty or is aborted
+	empty chan struct{}
+}
+
+// NewLoop creates an empty Loop
+func NewLoop() *Loop {
+	return &Loop{empty: make(chan struct{})}
+}
+
+// Abort stops waiting for the items in flight, e.g. because a node of the loop has returned and
+// won't consume the items that are sent to it. Aborting a nil Loop has no effect.
+func (l *Loop) Abort() {
+	if l == nil {
+		return
+	}
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	if !l.aborted {
+		l.aborted = true
+		l.notify()
+	}
+}
+
+// InFlight returns the number of items that are in flight in the loop
+func (l *Loop) InFlight() int64 {
+	return atomic.LoadInt64(&l.inFlight)
+}
+
+func (l *Loop) add(n int64) {
+	if atomic.AddInt64(&l.inFlight, n) == 0 {
+		l.mt.Lock()
+		l.notify()
+		l.mt.Unlock()
+	}
+}
+
+// notify the waiters of the loop. It must be invoked with the lock held
+func (l *Loop) notify() {
+	close(l.empty)
+	l.empty = make(chan struct{})
+}
+
+// drained returns whether the loop has no items in flight, or it has been aborted. Otherwise,
+// the returned channel is closed the next time that the loop becomes empty or is aborted.
+func (l *Loop) drained() (bool, <-chan struct{}) {
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	return l.aborted || l.InFlight() == 0, l.empty
+}
+
+// enter counts an item that is sent to the Joiner, if it belongs to a Loop
+func (j *Joiner[IN]) enter() {
+	if j.loop != nil {
+		j.loop.add(1)
+	}
+}
+
+// leave counts an item that is discarded by the Joiner, if it belongs to a Loop
+func (j *Joiner[IN]) leave() {
+	if j.loop != nil {
+		j.loop.add(-1)
+	}
+}
+
+// SetLoop specifies that the Joiner is the input of a node of a feedback Loop, so the items that
+// are sent to it are counted as in flight until they are consumed. The feedback Joiner (see
+// Feedback) belongs to the same Loop. It must be invoked before any sender acquires the Joiner.
+func (j *Joiner[IN]) SetLoop(l *Loop) {
+	j.loop = l
+	if j.feedback != nil {
+		j.feedback.loop = l
+	}
+}
+
+// source is the Loop of the node that sends data through a Forker, if any
+type source struct {
+	loop *Loop
+}
+
+// consumed counts an item as consumed from the Loop of the sender, once the Forker has sent it
+// to its joiners. This way, the loop never looks empty while the item is being forwarded.
+func (s *source) consumed() {
+	if s.loop != nil {
+		s.loop.add(-1)
+	}
+}
+
+// SetLoop specifies that the sender of the Forker is a node of a feedback Loop, and each item
+// that it sends is the result of consuming exactly one item that it has received. Each sent item
+// is counted as consumed from the Loop once it has been sent to the joiners.
+// It must be invoked before any sender acquires the Forker, and only for the Forkers that are
+// returned by Fork, ForkRoundRobin, ForkByKey, ForkLeastLoaded or Discard.
+func (f *Forker[OUT]) SetLoop(l *Loop) {
+	if f.source == nil {
+		// the sender is directly connected to a single joiner outside the loop, so we need
+		// a goroutine to count the items once they have been sent
+		out, release := f.sendCh, f.releaseChannel
+		sendCh := make(chan OUT, cap(out))
+		go func() {
+			for item := range sendCh {
+				out <- item
+				l.add(-1)
+			}
+			release()
+		}()
+		f.sendCh, f.releaseChannel = sendCh, func() { close(sendCh) }
+		f.source = &source{}
+	}
+	f.source.loop = l
+}
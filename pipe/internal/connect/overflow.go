@@ -0,0 +1,118 @@
+package connect
+
+import "sync/atomic"
+
+// OverflowPolicy specifies what a Forker does when it sends an item to a Joiner
+// whose channel is full.
+type OverflowPolicy int
+
+const (
+	// Block until the Joiner channel has room for the item
+	Block OverflowPolicy = iota
+	// DropNewest discards the item that is being sent
+	DropNewest
+	// DropOldest discards the oldest item in the Joiner channel, to make room
+	// for the item that is being sent. For unbuffered channels, it is equivalent to DropNewest.
+	DropOldest
+)
+
+// SetOverflow specifies what the senders do when the Joiner channel is full. If spill is not
+// nil, it is invoked for each discarded item. It must be invoked before any sender acquires the Joiner.
+func (j *Joiner[IN]) SetOverflow(policy OverflowPolicy, spill func(IN)) {
+	j.overflow = policy
+	j.spill = spill
+}
+
+// Dropped returns the number of items that have been discarded according to the OverflowPolicy
+func (j *Joiner[IN]) Dropped() uint64 {
+	return atomic.LoadUint64(&j.dropped)
+}
+
+func (j *Joiner[IN]) drop(item IN) {
+	atomic.AddUint64(&j.dropped, 1)
+	j.leave()
+	if j.spill != nil {
+		j.spill(item)
+	}
+}
+
+// edge connects the goroutine of a Forker with one of its Joiners. Edges to Joiners
+// with the Block policy have their own goroutine, which holds a single item while it waits
+// for room in the Joiner channel, so a slow receiver does not block the rest of the receivers
+// of the Forker until its channel is full. The held item is accounted by the Len and BufferLen
+// methods of the Joiner.
+type edge[T any] struct {
+	joiner *Joiner[T]
+	// out is the unbuffered queue of the edge goroutine, for blocking edges, or the Joiner channel otherwise
+	out chan T
+}
+
+func newEdges[T any](joiners []*Joiner[T]) []edge[T] {
+	edges := make([]edge[T], len(joiners))
+	for i, j := range joiners {
+		sender := j.AcquireSender()
+		if j.overflow != Block {
+			edges[i] = edge[T]{joiner: j, out: sender}
+			continue
+		}
+		queue := make(chan T)
+		atomic.AddInt32(&j.handoffs, 1)
+		go func(j *Joiner[T]) {
+			for item := range queue {
+				atomic.AddInt32(&j.handedOff, 1)
+				sender <- item
+				atomic.AddInt32(&j.handedOff, -1)
+			}
+			atomic.AddInt32(&j.handoffs, -1)
+			j.ReleaseSender()
+		}(j)
+		edges[i] = edge[T]{joiner: j, out: queue}
+	}
+	return edges
+}
+
+func (e *edge[T]) send(item T) {
+	e.joiner.enter()
+	switch e.joiner.overflow {
+	case DropNewest:
+		select {
+		case e.out <- item:
+		default:
+			e.joiner.drop(item)
+		}
+	case DropOldest:
+		for {
+			select {
+			case e.out <- item:
+				return
+			default:
+			}
+			if cap(e.out) == 0 {
+				e.joiner.drop(item)
+				return
+			}
+			select {
+			case old := <-e.out:
+				e.joiner.drop(old)
+			default:
+			}
+		}
+	default:
+		e.out <- item
+	}
+}
+
+// release the joiner once the edge has forwarded all the queued items
+func (e *edge[T]) release() {
+	if e.joiner.overflow == Block {
+		close(e.out)
+	} else {
+		e.joiner.ReleaseSender()
+	}
+}
+
+func releaseEdges[T any](edges []edge[T]) {
+	for i := range edges {
+		edges[i].release()
+	}
+}
@@ -0,0 +1,93 @@
+package connect
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats accumulates the metrics of the data that flows through a node.
+// Its fields must be accessed atomically.
+type Stats struct {
+	// ItemsIn counts the items that have been received by the node
+	ItemsIn uint64
+	// ItemsOut counts the items that have been sent by the node
+	ItemsOut uint64
+	// BlockedNanos accumulates the time that the node has been blocked sending data
+	// because the destination nodes weren't ready to receive it (backpressure)
+	BlockedNanos int64
+	// ProcessingNanos accumulates the time that the node has spent processing the
+	// ProcessingSamples items whose processing time could be measured
+	ProcessingNanos   int64
+	ProcessingSamples uint64
+}
+
+// Instrument returns a Forker that forwards the data to the provided Forker, counting the
+// sent items and the time that the sender is blocked sending them. It requires an extra
+// goroutine and an extra unbuffered channel.
+func Instrument[T any](stats *Stats, forker Forker[T]) Forker[T] {
+	sendCh := make(chan T)
+	go func() {
+		out := forker.AcquireSender()
+		for item := range sendCh {
+			start := time.Now()
+			out <- item
+			atomic.AddInt64(&stats.BlockedNanos, int64(time.Since(start)))
+			atomic.AddUint64(&stats.ItemsOut, 1)
+		}
+		forker.ReleaseSender()
+	}()
+	return Forker[T]{
+		sendCh:         sendCh,
+		releaseChannel: func() { close(sendCh) },
+	}
+}
+
+// InstrumentedReceiver returns a channel that forwards the items from the Receiver channel
+// of the Joiner, counting them and measuring the time that the node takes to process them.
+// It requires an extra goroutine and an extra unbuffered channel.
+// The processing time of an item is the time between the node receives it and the node is
+// ready to receive the next item. It can only be measured when the next item is already
+// available, or the node is still busy when it becomes available.
+func (j *Joiner[IN]) InstrumentedReceiver(stats *Stats) <-chan IN {
+	in, out := j.channel, make(chan IN)
+	go func() {
+		defer close(out)
+		var handoff time.Time
+		for {
+			var item IN
+			var ok bool
+			waited := false
+			select {
+			case item, ok = <-in:
+			default:
+				waited = true
+				item, ok = <-in
+			}
+			if !ok {
+				return
+			}
+			atomic.AddUint64(&stats.ItemsIn, 1)
+			select {
+			case out <- item:
+				// if we had to wait for the item, we don't know when the node finished
+				// processing the previous one
+				if !waited && !handoff.IsZero() {
+					sampleProcessing(stats, handoff)
+				}
+			default:
+				// the node is still processing the previous item
+				out <- item
+				if !handoff.IsZero() {
+					sampleProcessing(stats, handoff)
+				}
+			}
+			handoff = time.Now()
+		}
+	}()
+	return out
+}
+
+func sampleProcessing(stats *Stats, handoff time.Time) {
+	atomic.AddInt64(&stats.ProcessingNanos, int64(time.Since(handoff)))
+	atomic.AddUint64(&stats.ProcessingSamples, 1)
+}
@@ -0,0 +1,101 @@
+package connect
+
+import "sync"
+
+// Switch forwards the data from a sender node to a Forker that can be replaced while the
+// sender is running, so the node can be connected to other nodes without stopping it.
+// It requires an extra goroutine and an extra unbuffered channel.
+type Switch[T any] struct {
+	// mt is held while an item is sent to the forker, so it can't be replaced meanwhile
+	mt       sync.Mutex
+	forker   *Forker[T]
+	out      chan T
+	released bool
+
+	// tapsMt is only held while the taps are modified or an item is sent to them, which never
+	// blocks, so the taps can be added and removed while the forker is applying backpressure
+	tapsMt       sync.Mutex
+	tapsReleased bool
+	// taps receive a copy of each item, without blocking the sender
+	taps []edge[T]
+}
+
+// NewSwitch returns a Switch that forwards the data to the provided Forker, and the Forker
+// that must be used by the sender node.
+func NewSwitch[T any](forker Forker[T]) (*Switch[T], Forker[T]) {
+	s := &Switch[T]{forker: &forker}
+	s.out = s.forker.AcquireSender()
+	sendCh := make(chan T)
+	go func() {
+		for item := range sendCh {
+			s.mt.Lock()
+			s.out <- item
+			s.mt.Unlock()
+			s.tapsMt.Lock()
+			for i := range s.taps {
+				s.taps[i].send(item)
+			}
+			s.tapsMt.Unlock()
+		}
+		s.mt.Lock()
+		s.released = true
+		s.forker.ReleaseSender()
+		s.mt.Unlock()
+		s.tapsMt.Lock()
+		s.tapsReleased = true
+		releaseEdges(s.taps)
+		s.taps = nil
+		s.tapsMt.Unlock()
+	}()
+	return s, Forker[T]{
+		sendCh:         sendCh,
+		releaseChannel: func() { close(sendCh) },
+	}
+}
+
+// Replace the Forker that receives the data. The previous Forker is released after the
+// item that is being sent, if any, has been delivered to it, so each item is sent either to
+// the previous or to the new Forker. Replace blocks while that item can't be delivered.
+// It returns false, without using the new Forker, if the sender node has already released the Switch.
+func (s *Switch[T]) Replace(forker Forker[T]) bool {
+	s.mt.Lock()
+	defer s.mt.Unlock()
+	if s.released {
+		return false
+	}
+	previous := s.forker
+	s.forker = &forker
+	s.out = s.forker.AcquireSender()
+	previous.ReleaseSender()
+	return true
+}
+
+// AddTap sends a copy of each item to the Joiner, until RemoveTap is invoked or the sender
+// node releases the Switch. The Joiner must have an OverflowPolicy that discards the items
+// instead of blocking the sender. AddTap returns false, without acquiring the Joiner, if the
+// sender node has already released the Switch. It doesn't wait for the item that is being
+// sent to the Forker, if any, so the Joiner might not receive a copy of it.
+func (s *Switch[T]) AddTap(joiner *Joiner[T]) bool {
+	s.tapsMt.Lock()
+	defer s.tapsMt.Unlock()
+	if s.tapsReleased {
+		return false
+	}
+	s.taps = append(s.taps, newEdges([]*Joiner[T]{joiner})...)
+	return true
+}
+
+// RemoveTap stops sending items to the Joiner, and releases it. It has no effect if the
+// Joiner is not a tap of the Switch. As AddTap, it doesn't wait for the item that is being
+// sent to the Forker, if any.
+func (s *Switch[T]) RemoveTap(joiner *Joiner[T]) {
+	s.tapsMt.Lock()
+	defer s.tapsMt.Unlock()
+	for i := range s.taps {
+		if s.taps[i].joiner == joiner {
+			s.taps[i].release()
+			s.taps = append(s.taps[:i], s.taps[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,757 @@
+package pipe
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// Middle2 nodes are Middle nodes with two inputs of different types. Each input is fed by
+// its own set of senders, which are connected by passing In1 or In2 to their SendTo method.
+// Each input is closed independently, once all its senders have finished.
+type Middle2[IN1, IN2, OUT any] interface {
+	pipeNode
+	Sender[OUT]
+	// In1 returns the first input of the node
+	In1() Receiver[IN1]
+	// In2 returns the second input of the node
+	In2() Receiver[IN2]
+}
+
+// Middle3 nodes are Middle nodes with three inputs of different types.
+// See Middle2 for details.
+type Middle3[IN1, IN2, IN3, OUT any] interface {
+	pipeNode
+	Sender[OUT]
+	// In1 returns the first input of the node
+	In1() Receiver[IN1]
+	// In2 returns the second input of the node
+	In2() Receiver[IN2]
+	// In3 returns the third input of the node
+	In3() Receiver[IN3]
+}
+
+// Final2 nodes are Final nodes with two inputs of different types. Each input is fed by
+// its own set of senders, which are connected by passing In1 or In2 to their SendTo method.
+// Each input is closed independently, once all its senders have finished.
+type Final2[IN1, IN2 any] interface {
+	pipeNode
+	// In1 returns the first input of the node
+	In1() Receiver[IN1]
+	// In2 returns the second input of the node
+	In2() Receiver[IN2]
+}
+
+// Final3 nodes are Final nodes with three inputs of different types.
+// See Final2 for details.
+type Final3[IN1, IN2, IN3 any] interface {
+	pipeNode
+	// In1 returns the first input of the node
+	In1() Receiver[IN1]
+	// In2 returns the second input of the node
+	In2() Receiver[IN2]
+	// In3 returns the third input of the node
+	In3() Receiver[IN3]
+}
+
+// Middle2Func is a MiddleFunc with two input channels. It must process the inputs from
+// both input channels until they are closed. Each input channel can be closed at a different moment.
+type Middle2Func[IN1, IN2, OUT any] func(in1 <-chan IN1, in2 <-chan IN2, out chan<- OUT)
+
+// Middle3Func is a MiddleFunc with three input channels. It must process the inputs from
+// all the input channels until they are closed. Each input channel can be closed at a different moment.
+type Middle3Func[IN1, IN2, IN3, OUT any] func(in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3, out chan<- OUT)
+
+// Final2Func is a FinalFunc with two input channels. It must process the inputs from
+// both input channels until they are closed. Each input channel can be closed at a different moment.
+type Final2Func[IN1, IN2 any] func(in1 <-chan IN1, in2 <-chan IN2)
+
+// Final3Func is a FinalFunc with three input channels. It must process the inputs from
+// all the input channels until they are closed. Each input channel can be closed at a different moment.
+type Final3Func[IN1, IN2, IN3 any] func(in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3)
+
+// inputPort provides type-agnostic access to an input of a multi-input node
+type inputPort interface {
+	queue
+	inType() reflect.Type
+	isConnected() bool
+	setLoop(l *connect.Loop)
+}
+
+// multiInputNode is any node with multiple inputs
+type multiInputNode interface {
+	inputPorts() []inputPort
+}
+
+// input is one of the inputs of a multi-input node. It is the Receiver that the
+// senders of that input are connected to, and delegates the rest of the Receiver
+// behavior to its owner node.
+type input[IN any] struct {
+	node   startableReceiver
+	inputs connect.Joiner[IN]
+	// connected is set when the input is passed to the SendTo or FeedbackTo methods of any sender
+	connected bool
+}
+
+// startableReceiver is the part of the Receiver interface that does not depend on the input type
+type startableReceiver interface {
+	startable
+	isStarted() bool
+}
+
+func newInput[IN any](owner startableReceiver, options *creationOptions) *input[IN] {
+	return &input[IN]{
+		node:   owner,
+		inputs: connect.NewJoiner[IN](options.channelBufferLen),
+	}
+}
+
+func (i *input[IN]) setName(name string)              { i.node.setName(name) }
+func (i *input[IN]) nodeName() string                 { return i.node.nodeName() }
+func (i *input[IN]) kind() nodeKind                   { return i.node.kind() }
+func (i *input[IN]) isNil() bool                      { return i.node.isNil() }
+func (i *input[IN]) destinations() []pipeNode         { return i.node.destinations() }
+func (i *input[IN]) feedbackDestinations() []pipeNode { return i.node.feedbackDestinations() }
+func (i *input[IN]) describe() NodeInfo               { return i.node.describe() }
+func (i *input[IN]) nodeStats() NodeStats             { return i.node.nodeStats() }
+func (i *input[IN]) isStarted() bool                  { return i.node.isStarted() }
+func (i *input[IN]) start(rs *runState)               { i.node.start(rs) }
+
+func (i *input[IN]) joiners() []*connect.Joiner[IN] {
+	return []*connect.Joiner[IN]{&i.inputs}
+}
+
+func (i *input[IN]) feedbackJoiners() []*connect.Joiner[IN] {
+	return []*connect.Joiner[IN]{i.inputs.Feedback()}
+}
+
+func (i *input[IN]) Len() int                { return i.inputs.Len() }
+func (i *input[IN]) BufferLen() int          { return i.inputs.BufferLen() }
+func (i *input[IN]) Dropped() uint64         { return i.inputs.Dropped() }
+func (i *input[IN]) inType() reflect.Type    { return typeOf[IN]() }
+func (i *input[IN]) isConnected() bool       { return i.connected }
+func (i *input[IN]) setLoop(l *connect.Loop) { i.inputs.SetLoop(l) }
+
+// receiver returns the input channel, to be passed to the function of the owner node
+func (i *input[IN]) receiver(stats *connect.Stats) <-chan IN {
+	return receiver(&i.inputs, stats)
+}
+
+// appendReceivers appends receivers to the destinations of a sender. If any receiver
+// is the input of a multi-input node, it is marked as connected.
+func appendReceivers[T any](dsts []Receiver[T], receivers ...Receiver[T]) []Receiver[T] {
+	for _, r := range receivers {
+		if in, ok := r.(*input[T]); ok && in != nil {
+			in.connected = true
+		}
+	}
+	return append(dsts, receivers...)
+}
+
+// multiInput contains the properties that are common to all the multi-input nodes
+type multiInput struct {
+	name        string
+	ports       []inputPort
+	started     bool
+	panicPolicy PanicPolicy
+	stats       *connect.Stats
+	// loop is set if the node is part of a feedback loop
+	loop *connect.Loop
+	// spillMismatch is set if the OnOverflowSpill function does not accept any of the input types
+	spillMismatch bool
+}
+
+func newMultiInput(options *creationOptions) multiInput {
+	return multiInput{
+		panicPolicy: options.panicPolicy,
+		stats:       newStats(options),
+	}
+}
+
+// addInput creates a new input for the owner node, applying the overflow options to it
+func addInput[IN any](mi *multiInput, owner startableReceiver, options *creationOptions) *input[IN] {
+	in := newInput[IN](owner, options)
+	mi.ports = append(mi.ports, in)
+	// a spill function of a different type is reported by checkSpill only if it
+	// doesn't match any other input
+	setOverflow(&in.inputs, options)
+	return in
+}
+
+// checkSpill reports a mismatch if the OnOverflowSpill function does not match
+// the type of any of the inputs. Otherwise, it only applies to the inputs of its type,
+// and the rest of inputs drop the newest items.
+func (mi *multiInput) checkSpill(options *creationOptions) {
+	if options.overflowSpill == nil {
+		return
+	}
+	spillType := reflect.TypeOf(options.overflowSpill)
+	for _, p := range mi.ports {
+		if spillType.NumIn() == 1 && spillType.In(0) == p.inType() {
+			return
+		}
+	}
+	mi.spillMismatch = true
+}
+
+func (mi *multiInput) inputPorts() []inputPort {
+	return mi.ports
+}
+
+func (mi *multiInput) setLoop(l *connect.Loop) {
+	mi.loop = l
+	for _, p := range mi.ports {
+		p.setLoop(l)
+	}
+}
+
+func (mi *multiInput) describeInputs(kind NodeKind) NodeInfo {
+	ni := NodeInfo{
+		Name:             mi.name,
+		Kind:             kind,
+		In:               mi.ports[0].inType(),
+		ChannelBufferLen: mi.ports[0].BufferLen(),
+	}
+	for _, p := range mi.ports {
+		ni.Inputs = append(ni.Inputs, p.inType())
+	}
+	return ni
+}
+
+func (mi *multiInput) snapshot() NodeStats {
+	queues := make([]queue, 0, len(mi.ports))
+	for _, p := range mi.ports {
+		queues = append(queues, p)
+	}
+	return snapshot(mi.stats, queues...)
+}
+
+func (mi *multiInput) spillMatches() bool {
+	return !mi.spillMismatch
+}
+
+// multiOutput contains the outputs of the multi-input Middle nodes
+type multiOutput[OUT any] struct {
+	outs         []Receiver[OUT]
+	feedbackOuts []Receiver[OUT]
+	fanOut       fanOut
+}
+
+func (mo *multiOutput[OUT]) SendTo(outputs ...Receiver[OUT]) {
+	mo.outs = appendReceivers(mo.outs, outputs...)
+}
+
+func (mo *multiOutput[OUT]) FeedbackTo(outputs ...Receiver[OUT]) {
+	mo.feedbackOuts = appendReceivers(mo.feedbackOuts, outputs...)
+}
+
+func (mo *multiOutput[OUT]) destinations() []pipeNode {
+	return asPipeNodes(mo.outs)
+}
+
+func (mo *multiOutput[OUT]) feedbackDestinations() []pipeNode {
+	return asPipeNodes(mo.feedbackOuts)
+}
+
+// startOutputs starts the receivers of the node, returning nil if the node can't send data.
+// The returned function must be invoked to release the output channel.
+func (mo *multiOutput[OUT]) startOutputs(rs *runState, mi *multiInput) (chan OUT, func()) {
+	forker, err := startReceivers(rs, mi.stats, mo.fanOut, mi.loop, mo.outs, mo.feedbackOuts)
+	if err != nil {
+		rs.nodeError(mi.name, err)
+		if forker == nil {
+			return nil, nil
+		}
+	}
+	return forker.AcquireSender(), forker.ReleaseSender
+}
+
+// middle2 is a middle node with two inputs
+type middle2[IN1, IN2, OUT any] struct {
+	multiInput
+	multiOutput[OUT]
+	in1 *input[IN1]
+	in2 *input[IN2]
+	fun func(ctx context.Context, in1 <-chan IN1, in2 <-chan IN2, out chan<- OUT) error
+}
+
+func asMiddle2[IN1, IN2, OUT any](fun Middle2Func[IN1, IN2, OUT], opts ...Option) *middle2[IN1, IN2, OUT] {
+	options := getOptions(opts...)
+	m := &middle2[IN1, IN2, OUT]{
+		multiInput:  newMultiInput(&options),
+		multiOutput: multiOutput[OUT]{fanOut: fanOutOf(&options)},
+		fun: func(_ context.Context, in1 <-chan IN1, in2 <-chan IN2, out chan<- OUT) error {
+			fun(in1, in2, out)
+			return nil
+		},
+	}
+	m.in1 = addInput[IN1](&m.multiInput, m, &options)
+	m.in2 = addInput[IN2](&m.multiInput, m, &options)
+	m.checkSpill(&options)
+	return m
+}
+
+func (m *middle2[IN1, IN2, OUT]) In1() Receiver[IN1] { return m.in1 }
+func (m *middle2[IN1, IN2, OUT]) In2() Receiver[IN2] { return m.in2 }
+
+func (m *middle2[IN1, IN2, OUT]) setName(name string) { m.name = name }
+func (m *middle2[IN1, IN2, OUT]) nodeName() string    { return m.name }
+func (m *middle2[IN1, IN2, OUT]) kind() nodeKind      { return middleKind }
+func (m *middle2[IN1, IN2, OUT]) isNil() bool         { return false }
+func (m *middle2[IN1, IN2, OUT]) isStarted() bool     { return m.started }
+func (m *middle2[IN1, IN2, OUT]) nodeStats() NodeStats {
+	return m.snapshot()
+}
+
+func (m *middle2[IN1, IN2, OUT]) describe() NodeInfo {
+	ni := m.describeInputs(MiddleKind)
+	ni.Out = typeOf[OUT]()
+	return ni
+}
+
+func (m *middle2[IN1, IN2, OUT]) start(rs *runState) {
+	m.started = true
+	in1, in2 := m.in1.receiver(m.stats), m.in2.receiver(m.stats)
+	out, release := m.startOutputs(rs, &m.multiInput)
+	if out == nil {
+		go drain(in1)
+		go drain(in2)
+		return
+	}
+	rs.run(func() {
+		rs.runNode(m.name, m.panicPolicy, func() error {
+			defer m.loop.Abort()
+			return m.fun(rs.ctx, in1, in2, out)
+		})
+		release()
+		go drain(in1)
+		go drain(in2)
+	})
+}
+
+// middle3 is a middle node with three inputs
+type middle3[IN1, IN2, IN3, OUT any] struct {
+	multiInput
+	multiOutput[OUT]
+	in1 *input[IN1]
+	in2 *input[IN2]
+	in3 *input[IN3]
+	fun func(ctx context.Context, in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3, out chan<- OUT) error
+}
+
+func asMiddle3[IN1, IN2, IN3, OUT any](fun Middle3Func[IN1, IN2, IN3, OUT], opts ...Option) *middle3[IN1, IN2, IN3, OUT] {
+	options := getOptions(opts...)
+	m := &middle3[IN1, IN2, IN3, OUT]{
+		multiInput:  newMultiInput(&options),
+		multiOutput: multiOutput[OUT]{fanOut: fanOutOf(&options)},
+		fun: func(_ context.Context, in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3, out chan<- OUT) error {
+			fun(in1, in2, in3, out)
+			return nil
+		},
+	}
+	m.in1 = addInput[IN1](&m.multiInput, m, &options)
+	m.in2 = addInput[IN2](&m.multiInput, m, &options)
+	m.in3 = addInput[IN3](&m.multiInput, m, &options)
+	m.checkSpill(&options)
+	return m
+}
+
+func (m *middle3[IN1, IN2, IN3, OUT]) In1() Receiver[IN1] { return m.in1 }
+func (m *middle3[IN1, IN2, IN3, OUT]) In2() Receiver[IN2] { return m.in2 }
+func (m *middle3[IN1, IN2, IN3, OUT]) In3() Receiver[IN3] { return m.in3 }
+
+func (m *middle3[IN1, IN2, IN3, OUT]) setName(name string) { m.name = name }
+func (m *middle3[IN1, IN2, IN3, OUT]) nodeName() string    { return m.name }
+func (m *middle3[IN1, IN2, IN3, OUT]) kind() nodeKind      { return middleKind }
+func (m *middle3[IN1, IN2, IN3, OUT]) isNil() bool         { return false }
+func (m *middle3[IN1, IN2, IN3, OUT]) isStarted() bool     { return m.started }
+func (m *middle3[IN1, IN2, IN3, OUT]) nodeStats() NodeStats {
+	return m.snapshot()
+}
+
+func (m *middle3[IN1, IN2, IN3, OUT]) describe() NodeInfo {
+	ni := m.describeInputs(MiddleKind)
+	ni.Out = typeOf[OUT]()
+	return ni
+}
+
+func (m *middle3[IN1, IN2, IN3, OUT]) start(rs *runState) {
+	m.started = true
+	in1, in2, in3 := m.in1.receiver(m.stats), m.in2.receiver(m.stats), m.in3.receiver(m.stats)
+	out, release := m.startOutputs(rs, &m.multiInput)
+	if out == nil {
+		go drain(in1)
+		go drain(in2)
+		go drain(in3)
+		return
+	}
+	rs.run(func() {
+		rs.runNode(m.name, m.panicPolicy, func() error {
+			defer m.loop.Abort()
+			return m.fun(rs.ctx, in1, in2, in3, out)
+		})
+		release()
+		go drain(in1)
+		go drain(in2)
+		go drain(in3)
+	})
+}
+
+// final2 is a terminal node with two inputs
+type final2[IN1, IN2 any] struct {
+	multiInput
+	in1  *input[IN1]
+	in2  *input[IN2]
+	fun  func(ctx context.Context, in1 <-chan IN1, in2 <-chan IN2) error
+	done chan struct{}
+}
+
+func asFinal2[IN1, IN2 any](fun Final2Func[IN1, IN2], opts ...Option) *final2[IN1, IN2] {
+	if fun == nil {
+		return nil
+	}
+	options := getOptions(opts...)
+	f := &final2[IN1, IN2]{
+		multiInput: newMultiInput(&options),
+		fun: func(_ context.Context, in1 <-chan IN1, in2 <-chan IN2) error {
+			fun(in1, in2)
+			return nil
+		},
+		done: make(chan struct{}),
+	}
+	f.in1 = addInput[IN1](&f.multiInput, f, &options)
+	f.in2 = addInput[IN2](&f.multiInput, f, &options)
+	f.checkSpill(&options)
+	return f
+}
+
+// In1 returns the first input of the node. If the node is nil (e.g. ignored by
+// its provider), the returned receiver is ignored too.
+func (f *final2[IN1, IN2]) In1() Receiver[IN1] {
+	if f == nil {
+		return (*terminal[IN1])(nil)
+	}
+	return f.in1
+}
+
+// In2 returns the second input of the node. If the node is nil (e.g. ignored by
+// its provider), the returned receiver is ignored too.
+func (f *final2[IN1, IN2]) In2() Receiver[IN2] {
+	if f == nil {
+		return (*terminal[IN2])(nil)
+	}
+	return f.in2
+}
+
+func (f *final2[IN1, IN2]) setName(name string) {
+	if f != nil {
+		f.name = name
+	}
+}
+
+func (f *final2[IN1, IN2]) nodeName() string {
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
+func (f *final2[IN1, IN2]) kind() nodeKind                   { return finalKind }
+func (f *final2[IN1, IN2]) isNil() bool                      { return f == nil }
+func (f *final2[IN1, IN2]) destinations() []pipeNode         { return nil }
+func (f *final2[IN1, IN2]) feedbackDestinations() []pipeNode { return nil }
+func (f *final2[IN1, IN2]) describe() NodeInfo               { return f.describeInputs(FinalKind) }
+func (f *final2[IN1, IN2]) nodeStats() NodeStats             { return f.snapshot() }
+func (f *final2[IN1, IN2]) isStarted() bool                  { return f != nil && f.started }
+
+// Done returns a channel that is closed when the node function has returned.
+func (f *final2[IN1, IN2]) Done() <-chan struct{} {
+	if f == nil {
+		return closedChan()
+	}
+	return f.done
+}
+
+func (f *final2[IN1, IN2]) start(rs *runState) {
+	if f == nil {
+		return
+	}
+	f.started = true
+	in1, in2 := f.in1.receiver(f.stats), f.in2.receiver(f.stats)
+	rs.run(func() {
+		rs.runNode(f.name, f.panicPolicy, func() error {
+			return f.fun(rs.ctx, in1, in2)
+		})
+		close(f.done)
+		go drain(in1)
+		go drain(in2)
+	})
+}
+
+// final3 is a terminal node with three inputs
+type final3[IN1, IN2, IN3 any] struct {
+	multiInput
+	in1  *input[IN1]
+	in2  *input[IN2]
+	in3  *input[IN3]
+	fun  func(ctx context.Context, in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3) error
+	done chan struct{}
+}
+
+func asFinal3[IN1, IN2, IN3 any](fun Final3Func[IN1, IN2, IN3], opts ...Option) *final3[IN1, IN2, IN3] {
+	if fun == nil {
+		return nil
+	}
+	options := getOptions(opts...)
+	f := &final3[IN1, IN2, IN3]{
+		multiInput: newMultiInput(&options),
+		fun: func(_ context.Context, in1 <-chan IN1, in2 <-chan IN2, in3 <-chan IN3) error {
+			fun(in1, in2, in3)
+			return nil
+		},
+		done: make(chan struct{}),
+	}
+	f.in1 = addInput[IN1](&f.multiInput, f, &options)
+	f.in2 = addInput[IN2](&f.multiInput, f, &options)
+	f.in3 = addInput[IN3](&f.multiInput, f, &options)
+	f.checkSpill(&options)
+	return f
+}
+
+// In1 returns the first input of the node. If the node is nil (e.g. ignored by
+// its provider), the returned receiver is ignored too.
+func (f *final3[IN1, IN2, IN3]) In1() Receiver[IN1] {
+	if f == nil {
+		return (*terminal[IN1])(nil)
+	}
+	return f.in1
+}
+
+// In2 returns the second input of the node. If the node is nil (e.g. ignored by
+// its provider), the returned receiver is ignored too.
+func (f *final3[IN1, IN2, IN3]) In2() Receiver[IN2] {
+	if f == nil {
+		return (*terminal[IN2])(nil)
+	}
+	return f.in2
+}
+
+// In3 returns the third input of the node. If the node is nil (e.g. ignored by
+// its provider), the returned receiver is ignored too.
+func (f *final3[IN1, IN2, IN3]) In3() Receiver[IN3] {
+	if f == nil {
+		return (*terminal[IN3])(nil)
+	}
+	return f.in3
+}
+
+func (f *final3[IN1, IN2, IN3]) setName(name string) {
+	if f != nil {
+		f.name = name
+	}
+}
+
+func (f *final3[IN1, IN2, IN3]) nodeName() string {
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
+func (f *final3[IN1, IN2, IN3]) kind() nodeKind                   { return finalKind }
+func (f *final3[IN1, IN2, IN3]) isNil() bool                      { return f == nil }
+func (f *final3[IN1, IN2, IN3]) destinations() []pipeNode         { return nil }
+func (f *final3[IN1, IN2, IN3]) feedbackDestinations() []pipeNode { return nil }
+func (f *final3[IN1, IN2, IN3]) describe() NodeInfo               { return f.describeInputs(FinalKind) }
+func (f *final3[IN1, IN2, IN3]) nodeStats() NodeStats             { return f.snapshot() }
+func (f *final3[IN1, IN2, IN3]) isStarted() bool                  { return f != nil && f.started }
+
+// Done returns a channel that is closed when the node function has returned.
+func (f *final3[IN1, IN2, IN3]) Done() <-chan struct{} {
+	if f == nil {
+		return closedChan()
+	}
+	return f.done
+}
+
+func (f *final3[IN1, IN2, IN3]) start(rs *runState) {
+	if f == nil {
+		return
+	}
+	f.started = true
+	in1, in2, in3 := f.in1.receiver(f.stats), f.in2.receiver(f.stats), f.in3.receiver(f.stats)
+	rs.run(func() {
+		rs.runNode(f.name, f.panicPolicy, func() error {
+			return f.fun(rs.ctx, in1, in2, in3)
+		})
+		close(f.done)
+		go drain(in1)
+		go drain(in2)
+		go drain(in3)
+	})
+}
+
+func closedChan() <-chan struct{} {
+	closed := make(chan struct{})
+	close(closed)
+	return closed
+}
+
+// Middle2Ptr is a function that, given a NodesMap, returns a pointer to a Middle2 node,
+// which is going to be used as store destination when this function is passed as
+// argument to AddMiddle2 or AddMiddle2Provider functions.
+type Middle2Ptr[IMPL NodesMap, IN1, IN2, OUT any] func(IMPL) *Middle2[IN1, IN2, OUT]
+
+// Middle3Ptr is a function that, given a NodesMap, returns a pointer to a Middle3 node,
+// which is going to be used as store destination when this function is passed as
+// argument to AddMiddle3 or AddMiddle3Provider functions.
+type Middle3Ptr[IMPL NodesMap, IN1, IN2, IN3, OUT any] func(IMPL) *Middle3[IN1, IN2, IN3, OUT]
+
+// Final2Ptr is a function that, given a NodesMap, returns a pointer to a Final2 node,
+// which is going to be used as store destination when this function is passed as
+// argument to AddFinal2 or AddFinal2Provider functions.
+type Final2Ptr[IMPL NodesMap, IN1, IN2 any] func(IMPL) *Final2[IN1, IN2]
+
+// Final3Ptr is a function that, given a NodesMap, returns a pointer to a Final3 node,
+// which is going to be used as store destination when this function is passed as
+// argument to AddFinal3 or AddFinal3Provider functions.
+type Final3Ptr[IMPL NodesMap, IN1, IN2, IN3 any] func(IMPL) *Final3[IN1, IN2, IN3]
+
+// Middle2Provider is a function that returns a Middle2Func to be used as
+// Middle2 node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// The returned function can't be nil unless an error is returned.
+type Middle2Provider[IN1, IN2, OUT any] func() (Middle2Func[IN1, IN2, OUT], error)
+
+// Middle3Provider is a function that returns a Middle3Func to be used as
+// Middle3 node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// The returned function can't be nil unless an error is returned.
+type Middle3Provider[IN1, IN2, IN3, OUT any] func() (Middle3Func[IN1, IN2, IN3, OUT], error)
+
+// Final2Provider is a function that returns a Final2Func to be used as
+// Final2 node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// If both the returned function and the error are nil, the node will be ignored.
+type Final2Provider[IN1, IN2 any] func() (Final2Func[IN1, IN2], error)
+
+// Final3Provider is a function that returns a Final3Func to be used as
+// Final3 node in a pipeline. It also might return an error if there is a
+// problem with the configuration or instantiation of the function.
+// If both the returned function and the error are nil, the node will be ignored.
+type Final3Provider[IN1, IN2, IN3 any] func() (Final3Func[IN1, IN2, IN3], error)
+
+// AddMiddle2 creates a Middle2 node given the provided Middle2Func. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided Middle2Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddle2[IMPL NodesMap, IN1, IN2, OUT any](p *Builder[IMPL], field Middle2Ptr[IMPL, IN1, IN2, OUT], fn Middle2Func[IN1, IN2, OUT], opts ...Option) {
+	middleNode := asMiddle2(fn, p.joinOpts(opts...)...)
+	dstAddress := field(p.nodesMap)
+	p.middleNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[pipeNode]{node: middleNode}
+	*(dstAddress) = middleNode
+}
+
+// AddMiddle2Provider registers a Middle2Provider into the pipeline Builder.
+// The function returned by the Middle2Provider will be assigned to the NodesMap
+// field whose pointer is returned by the passed Middle2Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddle2Provider[IMPL NodesMap, IN1, IN2, OUT any](p *Builder[IMPL], field Middle2Ptr[IMPL, IN1, IN2, OUT], provider Middle2Provider[IN1, IN2, OUT], opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.middleNodes[dstAddress] = nodeOrProvider[pipeNode]{
+		provider: &reflectProvider{
+			asNode:      reflect.ValueOf(asMiddle2[IN1, IN2, OUT]),
+			fieldGetter: reflect.ValueOf(field),
+			fn:          reflect.ValueOf(provider),
+			opts:        p.joinOpts(opts...),
+		}}
+}
+
+// AddMiddle3 creates a Middle3 node given the provided Middle3Func. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided Middle3Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddle3[IMPL NodesMap, IN1, IN2, IN3, OUT any](p *Builder[IMPL], field Middle3Ptr[IMPL, IN1, IN2, IN3, OUT], fn Middle3Func[IN1, IN2, IN3, OUT], opts ...Option) {
+	middleNode := asMiddle3(fn, p.joinOpts(opts...)...)
+	dstAddress := field(p.nodesMap)
+	p.middleNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[pipeNode]{node: middleNode}
+	*(dstAddress) = middleNode
+}
+
+// AddMiddle3Provider registers a Middle3Provider into the pipeline Builder.
+// The function returned by the Middle3Provider will be assigned to the NodesMap
+// field whose pointer is returned by the passed Middle3Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used. The Parallelism option is ignored.
+func AddMiddle3Provider[IMPL NodesMap, IN1, IN2, IN3, OUT any](p *Builder[IMPL], field Middle3Ptr[IMPL, IN1, IN2, IN3, OUT], provider Middle3Provider[IN1, IN2, IN3, OUT], opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.middleNodes[dstAddress] = nodeOrProvider[pipeNode]{
+		provider: &reflectProvider{
+			asNode:      reflect.ValueOf(asMiddle3[IN1, IN2, IN3, OUT]),
+			fieldGetter: reflect.ValueOf(field),
+			fn:          reflect.ValueOf(provider),
+			opts:        p.joinOpts(opts...),
+		}}
+}
+
+// AddFinal2 creates a Final2 node given the provided Final2Func. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided Final2Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinal2[IMPL NodesMap, IN1, IN2 any](p *Builder[IMPL], field Final2Ptr[IMPL, IN1, IN2], fn Final2Func[IN1, IN2], opts ...Option) {
+	termNode := asFinal2(fn, p.joinOpts(opts...)...)
+	dstAddress := field(p.nodesMap)
+	p.finalNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[doneable]{node: termNode}
+	*(dstAddress) = termNode
+}
+
+// AddFinal2Provider registers a Final2Provider into the pipeline Builder.
+// The function returned by the Final2Provider will be assigned to the NodesMap
+// field whose pointer is returned by the passed Final2Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinal2Provider[IMPL NodesMap, IN1, IN2 any](p *Builder[IMPL], field Final2Ptr[IMPL, IN1, IN2], provider Final2Provider[IN1, IN2], opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.finalNodes[dstAddress] = nodeOrProvider[doneable]{
+		provider: &reflectProvider{
+			acceptNilFunc: true,
+			asNode:        reflect.ValueOf(asFinal2[IN1, IN2]),
+			fieldGetter:   reflect.ValueOf(field),
+			fn:            reflect.ValueOf(provider),
+			opts:          p.joinOpts(opts...),
+		}}
+}
+
+// AddFinal3 creates a Final3 node given the provided Final3Func. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided Final3Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinal3[IMPL NodesMap, IN1, IN2, IN3 any](p *Builder[IMPL], field Final3Ptr[IMPL, IN1, IN2, IN3], fn Final3Func[IN1, IN2, IN3], opts ...Option) {
+	termNode := asFinal3(fn, p.joinOpts(opts...)...)
+	dstAddress := field(p.nodesMap)
+	p.finalNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[doneable]{node: termNode}
+	*(dstAddress) = termNode
+}
+
+// AddFinal3Provider registers a Final3Provider into the pipeline Builder.
+// The function returned by the Final3Provider will be assigned to the NodesMap
+// field whose pointer is returned by the passed Final3Ptr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinal3Provider[IMPL NodesMap, IN1, IN2, IN3 any](p *Builder[IMPL], field Final3Ptr[IMPL, IN1, IN2, IN3], provider Final3Provider[IN1, IN2, IN3], opts ...Option) {
+	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
+	p.finalNodes[dstAddress] = nodeOrProvider[doneable]{
+		provider: &reflectProvider{
+			acceptNilFunc: true,
+			asNode:        reflect.ValueOf(asFinal3[IN1, IN2, IN3]),
+			fieldGetter:   reflect.ValueOf(field),
+			fn:            reflect.ValueOf(provider),
+			opts:          p.joinOpts(opts...),
+		}}
+}
@@ -0,0 +1,199 @@
+package pipe_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+type enrichPipe struct {
+	config   pipe.Start[string]
+	events   pipe.Start[int]
+	enricher pipe.Middle2[string, int, string]
+	final    pipe.Final[string]
+}
+
+func (e *enrichPipe) Connect() {
+	e.config.SendTo(e.enricher.In1())
+	e.events.SendTo(e.enricher.In2())
+	e.enricher.SendTo(e.final)
+}
+
+func enrichBuilder(collected *[]string) *pipe.Builder[*enrichPipe] {
+	b := pipe.NewBuilder(&enrichPipe{})
+	pipe.AddStart(b, func(e *enrichPipe) *pipe.Start[string] { return &e.config }, func(out chan<- string) {
+		out <- "a"
+	})
+	pipe.AddStart(b, func(e *enrichPipe) *pipe.Start[int] { return &e.events }, Counter(1, 3))
+	pipe.AddMiddle2(b, func(e *enrichPipe) *pipe.Middle2[string, int, string] { return &e.enricher },
+		func(config <-chan string, events <-chan int, out chan<- string) {
+			// the events are not processed until the configuration is received
+			prefix := <-config
+			for config != nil || events != nil {
+				select {
+				case c, ok := <-config:
+					if !ok {
+						config = nil
+						continue
+					}
+					prefix = c
+				case e, ok := <-events:
+					if !ok {
+						events = nil
+						continue
+					}
+					out <- prefix + strconv.Itoa(e)
+				}
+			}
+		})
+	pipe.AddFinal(b, func(e *enrichPipe) *pipe.Final[string] { return &e.final }, func(in <-chan string) {
+		for i := range in {
+			*collected = append(*collected, i)
+		}
+	})
+	return b
+}
+
+func TestMiddle2(t *testing.T) {
+	var collected []string
+	r, err := enrichBuilder(&collected).Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	assert.Equal(t, []string{"a1", "a2", "a3"}, collected)
+}
+
+func TestMiddle2_Graph(t *testing.T) {
+	var collected []string
+	r, err := enrichBuilder(&collected).Build()
+	require.NoError(t, err)
+
+	g := r.Graph()
+	require.Len(t, g.Nodes, 4)
+	enricher := g.Nodes[2]
+	assert.Equal(t, "enricher", enricher.Name)
+	assert.Equal(t, pipe.MiddleKind, enricher.Kind)
+	assert.Equal(t, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(0)}, enricher.Inputs)
+	assert.Equal(t, reflect.TypeOf(""), enricher.Out)
+	assert.Equal(t, []pipe.Edge{
+		{From: "config", To: "enricher", Type: reflect.TypeOf("")},
+		{From: "events", To: "enricher", Type: reflect.TypeOf(0)},
+		{From: "enricher", To: "final", Type: reflect.TypeOf("")},
+	}, g.Edges)
+}
+
+type joinPipe struct {
+	names  pipe.Start[string]
+	ages   pipe.Start[int]
+	scores pipe.Start[float64]
+	pairs  pipe.Final2[string, int]
+	triple pipe.Final3[string, int, float64]
+}
+
+func (j *joinPipe) Connect() {
+	j.names.SendTo(j.pairs.In1(), j.triple.In1())
+	j.ages.SendTo(j.pairs.In2(), j.triple.In2())
+	j.scores.SendTo(j.triple.In3())
+}
+
+func TestFinal2_Final3(t *testing.T) {
+	b := pipe.NewBuilder(&joinPipe{})
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[string] { return &j.names }, func(out chan<- string) {
+		out <- "ana"
+		out <- "bob"
+	})
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[int] { return &j.ages }, Counter(30, 31))
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[float64] { return &j.scores }, func(out chan<- float64) {
+		out <- 7.5
+	})
+	var pairs, triples []string
+	pipe.AddFinal2(b, func(j *joinPipe) *pipe.Final2[string, int] { return &j.pairs },
+		func(names <-chan string, ages <-chan int) {
+			for name := range names {
+				pairs = append(pairs, name+":"+strconv.Itoa(<-ages))
+			}
+		})
+	pipe.AddFinal3Provider(b, func(j *joinPipe) *pipe.Final3[string, int, float64] { return &j.triple },
+		func() (pipe.Final3Func[string, int, float64], error) {
+			return func(names <-chan string, ages <-chan int, scores <-chan float64) {
+				for name := range names {
+					triples = append(triples, name+":"+strconv.Itoa(<-ages))
+				}
+				for s := range scores {
+					triples = append(triples, strconv.FormatFloat(s, 'f', 1, 64))
+				}
+			}, nil
+		})
+	r, err := b.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	assert.Equal(t, []string{"ana:30", "bob:31"}, pairs)
+	assert.Equal(t, []string{"ana:30", "bob:31", "7.5"}, triples)
+}
+
+func TestFinal2_Ignored(t *testing.T) {
+	b := pipe.NewBuilder(&joinPipe{})
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[string] { return &j.names }, func(out chan<- string) {
+		out <- "ana"
+	})
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[int] { return &j.ages }, Counter(30, 30))
+	pipe.AddStart(b, func(j *joinPipe) *pipe.Start[float64] { return &j.scores }, func(out chan<- float64) {
+		out <- 7.5
+	})
+	pipe.AddFinal2Provider(b, func(j *joinPipe) *pipe.Final2[string, int] { return &j.pairs },
+		func() (pipe.Final2Func[string, int], error) {
+			return nil, nil
+		})
+	var scores []float64
+	pipe.AddFinal3(b, func(j *joinPipe) *pipe.Final3[string, int, float64] { return &j.triple },
+		func(names <-chan string, ages <-chan int, in <-chan float64) {
+			for s := range in {
+				scores = append(scores, s)
+			}
+		})
+	r, err := b.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	assert.Equal(t, []float64{7.5}, scores)
+	assert.Len(t, r.Graph().Nodes, 4)
+}
+
+func TestMiddle2_UnconnectedInput(t *testing.T) {
+	b := pipe.NewBuilder(&unconnectedPipe{})
+	pipe.AddStart(b, func(u *unconnectedPipe) *pipe.Start[int] { return &u.events }, Counter(1, 3))
+	pipe.AddMiddle2(b, func(u *unconnectedPipe) *pipe.Middle2[string, int, int] { return &u.enricher },
+		func(_ <-chan string, in <-chan int, out chan<- int) {
+			for i := range in {
+				out <- i
+			}
+		})
+	pipe.AddFinal(b, func(u *unconnectedPipe) *pipe.Final[int] { return &u.final }, func(in <-chan int) {})
+	_, err := b.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"enricher.in1"}, verr.UnconnectedInputs)
+	assert.Empty(t, verr.Unreachable)
+	assert.Empty(t, verr.Dangling)
+}
+
+type unconnectedPipe struct {
+	events   pipe.Start[int]
+	enricher pipe.Middle2[string, int, int]
+	final    pipe.Final[int]
+}
+
+func (u *unconnectedPipe) Connect() {
+	u.events.SendTo(u.enricher.In2())
+	u.enricher.SendTo(u.final)
+}
@@ -4,7 +4,9 @@
 package pipe
 
 import (
+	"context"
 	"errors"
+	"sync/atomic"
 
 	"github.com/mariomac/pipes/pipe/internal/connect"
 )
@@ -22,20 +24,73 @@ type MiddleFunc[IN, OUT any] func(in <-chan IN, out chan<- OUT)
 // It must process the inputs from the input channel until it's closed.
 type FinalFunc[IN any] func(in <-chan IN)
 
+// StartFuncCtx is a StartFunc that also receives, as first argument, the context of the
+// Runner. The function must return when the context is cancelled (e.g. after invoking
+// Runner.Stop), so the pipeline can be drained and finished.
+type StartFuncCtx[OUT any] func(ctx context.Context, out chan<- OUT)
+
+// MiddleFuncCtx is a MiddleFunc that also receives, as first argument, the context of the
+// Runner. Despite the context is cancelled, the function should keep processing the
+// inputs from the input channel until it's closed, so the pipeline is properly drained.
+type MiddleFuncCtx[IN, OUT any] func(ctx context.Context, in <-chan IN, out chan<- OUT)
+
+// FinalFuncCtx is a FinalFunc that also receives, as first argument, the context of the
+// Runner. Despite the context is cancelled, the function should keep processing the
+// inputs from the input channel until it's closed, so the pipeline is properly drained.
+type FinalFuncCtx[IN any] func(ctx context.Context, in <-chan IN)
+
+// StartFuncErr is a StartFunc that can return an error. The error is reported to the
+// Runner (see Runner.Err and Runner.Wait) and the output of the node is closed.
+type StartFuncErr[OUT any] func(out chan<- OUT) error
+
+// MiddleFuncErr is a MiddleFunc that can return an error. The error is reported to the
+// Runner (see Runner.Err and Runner.Wait) and the output of the node is closed. Any
+// data that is still pending in the input channel is discarded.
+type MiddleFuncErr[IN, OUT any] func(in <-chan IN, out chan<- OUT) error
+
+// FinalFuncErr is a FinalFunc that can return an error. The error is reported to the
+// Runner (see Runner.Err and Runner.Wait). Any data that is still pending in the
+// input channel is discarded.
+type FinalFuncErr[IN any] func(in <-chan IN) error
+
+// startFn, middleFn and finalFn are the internal representations of all the
+// StartFunc, MiddleFunc and FinalFunc variants.
+type startFn[OUT any] func(ctx context.Context, out chan<- OUT) error
+type middleFn[IN, OUT any] func(ctx context.Context, in <-chan IN, out chan<- OUT) error
+type finalFn[IN any] func(ctx context.Context, in <-chan IN) error
+
 // Sender is any node that can send data to another node: Start or Middle.
 type Sender[OUT any] interface {
 	// SendTo connects a Sender with a group of Receiver instances.
-	SendTo(r ...Receiver[OUT]) // TODO: fail if there is any middle or final node not being destination of any "SendTo"
+	SendTo(r ...Receiver[OUT])
+	// FeedbackTo connects a Sender with a group of Receiver instances that are
+	// upstream of it, closing a feedback loop (e.g. for retrying the processing of
+	// some items). Loops that are created by means of SendTo would never finish, so
+	// the Builder would reject them.
+	// A Receiver that is the destination of a feedback loop will close its input
+	// once all its non-feedback senders have finished, and there are no items in
+	// flight in the loop. To account for the items in flight, each node of the loop
+	// must send exactly one item for each item that it receives (e.g. by means of
+	// a MiddleDemux node that routes each item either back through the loop or out
+	// of it). Otherwise, the loop might never finish, or might be closed early and
+	// discard the items that are sent back. If any node of the loop returns before
+	// its input is closed (e.g. because of an error or a panic), the loop is closed
+	// without waiting for the items in flight.
+	FeedbackTo(r ...Receiver[OUT])
 }
 
 // Receiver is any node that can receive data from another node: Middle or Final nodes
 type Receiver[IN any] interface {
+	pipeNode
 	isStarted() bool
-	start()
+	start(rs *runState)
 	// joiners will usually return only one joiner instance but in
 	// the case of a BypassNode, which might return the joiners of
 	// all their destination nodes
 	joiners() []*connect.Joiner[IN]
+	// feedbackJoiners return the joiners that are used when the receiver is
+	// the destination of a feedback loop.
+	feedbackJoiners() []*connect.Joiner[IN]
 }
 
 // Start nodes insert data into the pipeline. They only can send data to the pipeline, despite they
@@ -63,21 +118,93 @@ type Final[IN any] interface {
 // An start node must have at least one output node.
 type start[OUT any] struct {
 	receiverGroup[OUT]
-	fun StartFunc[OUT]
+	name        string
+	fun         startFn[OUT]
+	panicPolicy PanicPolicy
+	stats       *connect.Stats
+	fanOut      fanOut
+	// output allows replacing the destinations of the running node (see Reconfigurable)
+	output *connect.Switch[OUT]
+	// cancel the context of the running node
+	cancel context.CancelFunc
+	// running is the node that this node replaces in a running pipeline (see Runner.Reconfigure)
+	running *start[OUT]
 }
 
 // middle is any intermediate node that receives data from another node, processes/filters it,
 // and forwards the data to another node.
 // An middle node must have at least one output node.
 type middle[IN, OUT any] struct {
-	outs    []Receiver[OUT]
-	inputs  connect.Joiner[IN]
-	started bool
-	fun     MiddleFunc[IN, OUT]
+	name         string
+	outs         []Receiver[OUT]
+	feedbackOuts []Receiver[OUT]
+	inputs       connect.Joiner[IN]
+	started      bool
+	fun          middleFn[IN, OUT]
+	panicPolicy  PanicPolicy
+	stats        *connect.Stats
+	parallelism  int
+	fanOut       fanOut
+	// loop is set if the node is part of a feedback loop
+	loop *connect.Loop
+	// spillMismatch is set if the OnOverflowSpill function does not accept the input type
+	spillMismatch bool
+	// output allows replacing the destinations of the running node (see Reconfigurable)
+	output *connect.Switch[OUT]
+	// running is the node that this node replaces in a running pipeline (see Runner.Reconfigure)
+	running *middle[IN, OUT]
+}
+
+func (m *middle[IN, OUT]) setName(name string) {
+	m.name = name
+}
+
+func (m *middle[IN, OUT]) nodeName() string {
+	return m.name
+}
+
+func (m *middle[IN, OUT]) kind() nodeKind {
+	return middleKind
+}
+
+func (m *middle[IN, OUT]) isNil() bool {
+	return false
+}
+
+func (m *middle[IN, OUT]) destinations() []pipeNode {
+	return asPipeNodes(m.outs)
+}
+
+func (m *middle[IN, OUT]) feedbackDestinations() []pipeNode {
+	return asPipeNodes(m.feedbackOuts)
+}
+
+func (m *middle[IN, OUT]) describe() NodeInfo {
+	return NodeInfo{
+		Name:             m.name,
+		Kind:             MiddleKind,
+		In:               typeOf[IN](),
+		Out:              typeOf[OUT](),
+		ChannelBufferLen: m.live().inputs.BufferLen(),
+	}
+}
+
+func (m *middle[IN, OUT]) nodeStats() NodeStats {
+	live := m.live()
+	return snapshot(live.stats, &live.inputs)
 }
 
 func (m *middle[IN, OUT]) joiners() []*connect.Joiner[IN] {
-	return []*connect.Joiner[IN]{&m.inputs}
+	return []*connect.Joiner[IN]{&m.live().inputs}
+}
+
+func (m *middle[IN, OUT]) feedbackJoiners() []*connect.Joiner[IN] {
+	return []*connect.Joiner[IN]{m.live().inputs.Feedback()}
+}
+
+func (m *middle[IN, OUT]) setLoop(l *connect.Loop) {
+	m.loop = l
+	m.inputs.SetLoop(l)
 }
 
 func (m *middle[IN, OUT]) isStarted() bool {
@@ -85,23 +212,84 @@ func (m *middle[IN, OUT]) isStarted() bool {
 }
 
 func (m *middle[IN, OUT]) SendTo(outputs ...Receiver[OUT]) {
-	m.outs = append(m.outs, outputs...)
+	m.outs = appendReceivers(m.outs, outputs...)
+}
+
+func (m *middle[IN, OUT]) FeedbackTo(outputs ...Receiver[OUT]) {
+	m.feedbackOuts = appendReceivers(m.feedbackOuts, outputs...)
 }
 
 // terminal is any node that receives data from another node and does not forward it to another node,
 // but can process it and send the results to outside the pipeline (e.g. memory, storage, web...)
 type terminal[IN any] struct {
-	inputs  connect.Joiner[IN]
-	started bool
-	fun     FinalFunc[IN]
-	done    chan struct{}
+	name        string
+	inputs      connect.Joiner[IN]
+	started     bool
+	fun         finalFn[IN]
+	panicPolicy PanicPolicy
+	stats       *connect.Stats
+	// spillMismatch is set if the OnOverflowSpill function does not accept the input type
+	spillMismatch bool
+	done          chan struct{}
+	// running is the node that this node replaces in a running pipeline (see Runner.Reconfigure)
+	running *terminal[IN]
+}
+
+func (t *terminal[IN]) setName(name string) {
+	if t != nil {
+		t.name = name
+	}
+}
+
+func (t *terminal[IN]) nodeName() string {
+	if t == nil {
+		return ""
+	}
+	return t.name
+}
+
+func (t *terminal[IN]) kind() nodeKind {
+	return finalKind
+}
+
+func (t *terminal[IN]) isNil() bool {
+	return t == nil
+}
+
+func (t *terminal[IN]) destinations() []pipeNode {
+	return nil
+}
+
+func (t *terminal[IN]) feedbackDestinations() []pipeNode {
+	return nil
+}
+
+func (t *terminal[IN]) describe() NodeInfo {
+	return NodeInfo{
+		Name:             t.name,
+		Kind:             FinalKind,
+		In:               typeOf[IN](),
+		ChannelBufferLen: t.live().inputs.BufferLen(),
+	}
+}
+
+func (t *terminal[IN]) nodeStats() NodeStats {
+	live := t.live()
+	return snapshot(live.stats, &live.inputs)
 }
 
 func (t *terminal[IN]) joiners() []*connect.Joiner[IN] {
 	if t == nil {
 		return nil
 	}
-	return []*connect.Joiner[IN]{&t.inputs}
+	return []*connect.Joiner[IN]{&t.live().inputs}
+}
+
+func (t *terminal[IN]) feedbackJoiners() []*connect.Joiner[IN] {
+	if t == nil {
+		return nil
+	}
+	return []*connect.Joiner[IN]{t.live().inputs.Feedback()}
 }
 
 func (t *terminal[IN]) isStarted() bool {
@@ -117,32 +305,89 @@ func (t *terminal[IN]) isStarted() bool {
 // previous stages have ended
 func (t *terminal[IN]) Done() <-chan struct{} {
 	if t == nil {
-		closed := make(chan struct{})
-		close(closed)
-		return closed
+		return closedChan()
+	}
+	return t.live().done
+}
+
+// asStart wraps a StartFunc into a start node.
+func asStart[OUT any](fun StartFunc[OUT], opts ...Option) *start[OUT] {
+	if fun == nil {
+		return nil
+	}
+	return newStart(func(_ context.Context, out chan<- OUT) error {
+		fun(out)
+		return nil
+	}, opts...)
+}
+
+// asStartCtx wraps a StartFuncCtx into a start node.
+func asStartCtx[OUT any](fun StartFuncCtx[OUT], opts ...Option) *start[OUT] {
+	if fun == nil {
+		return nil
 	}
-	return t.done
+	return newStart(func(ctx context.Context, out chan<- OUT) error {
+		fun(ctx, out)
+		return nil
+	}, opts...)
 }
 
-// asStart wraps a group of StartFunc with the same signature into a start node.
-// TODO: let just 1 start function as argument
-func asStart[OUT any](fun StartFunc[OUT]) *start[OUT] {
+// asStartErr wraps a StartFuncErr into a start node.
+func asStartErr[OUT any](fun StartFuncErr[OUT], opts ...Option) *start[OUT] {
 	if fun == nil {
 		return nil
 	}
+	return newStart(func(_ context.Context, out chan<- OUT) error {
+		return fun(out)
+	}, opts...)
+}
+
+func newStart[OUT any](fun startFn[OUT], opts ...Option) *start[OUT] {
+	options := getOptions(opts...)
 	return &start[OUT]{
 		fun:           fun,
+		panicPolicy:   options.panicPolicy,
+		stats:         newStats(&options),
+		fanOut:        fanOutOf(&options),
 		receiverGroup: receiverGroup[OUT]{},
 	}
 }
 
 // asMiddle wraps an MiddleFunc into an middle node.
 func asMiddle[IN, OUT any](fun MiddleFunc[IN, OUT], opts ...Option) *middle[IN, OUT] {
+	return newMiddle(func(_ context.Context, in <-chan IN, out chan<- OUT) error {
+		fun(in, out)
+		return nil
+	}, opts...)
+}
+
+// asMiddleCtx wraps an MiddleFuncCtx into an middle node.
+func asMiddleCtx[IN, OUT any](fun MiddleFuncCtx[IN, OUT], opts ...Option) *middle[IN, OUT] {
+	return newMiddle(func(ctx context.Context, in <-chan IN, out chan<- OUT) error {
+		fun(ctx, in, out)
+		return nil
+	}, opts...)
+}
+
+// asMiddleErr wraps an MiddleFuncErr into an middle node.
+func asMiddleErr[IN, OUT any](fun MiddleFuncErr[IN, OUT], opts ...Option) *middle[IN, OUT] {
+	return newMiddle(func(_ context.Context, in <-chan IN, out chan<- OUT) error {
+		return fun(in, out)
+	}, opts...)
+}
+
+func newMiddle[IN, OUT any](fun middleFn[IN, OUT], opts ...Option) *middle[IN, OUT] {
 	options := getOptions(opts...)
-	return &middle[IN, OUT]{
-		inputs: connect.NewJoiner[IN](options.channelBufferLen),
-		fun:    fun,
+	m := &middle[IN, OUT]{
+		inputs:      connect.NewJoiner[IN](options.channelBufferLen),
+		fun:         fun,
+		panicPolicy: options.panicPolicy,
+		stats:       newStats(&options),
+		parallelism: options.parallelism,
+		fanOut:      fanOutOf(&options),
 	}
+	m.spillMismatch = !setOverflow(&m.inputs, &options)
+	return m
 }
 
 // asFinal wraps a FinalFunc into a terminal node.
@@ -150,62 +395,215 @@ func asFinal[IN any](fun FinalFunc[IN], opts ...Option) *terminal[IN] {
 	if fun == nil {
 		return nil
 	}
+	return newFinal(func(_ context.Context, in <-chan IN) error {
+		fun(in)
+		return nil
+	}, opts...)
+}
+
+// asFinalCtx wraps a FinalFuncCtx into a terminal node.
+func asFinalCtx[IN any](fun FinalFuncCtx[IN], opts ...Option) *terminal[IN] {
+	if fun == nil {
+		return nil
+	}
+	return newFinal(func(ctx context.Context, in <-chan IN) error {
+		fun(ctx, in)
+		return nil
+	}, opts...)
+}
+
+// asFinalErr wraps a FinalFuncErr into a terminal node.
+func asFinalErr[IN any](fun FinalFuncErr[IN], opts ...Option) *terminal[IN] {
+	if fun == nil {
+		return nil
+	}
+	return newFinal(func(_ context.Context, in <-chan IN) error {
+		return fun(in)
+	}, opts...)
+}
+
+func newFinal[IN any](fun finalFn[IN], opts ...Option) *terminal[IN] {
 	options := getOptions(opts...)
-	return &terminal[IN]{
-		inputs: connect.NewJoiner[IN](options.channelBufferLen),
-		fun:    fun,
-		done:   make(chan struct{}),
+	t := &terminal[IN]{
+		inputs:      connect.NewJoiner[IN](options.channelBufferLen),
+		fun:         fun,
+		panicPolicy: options.panicPolicy,
+		stats:       newStats(&options),
+		done:        make(chan struct{}),
 	}
+	t.spillMismatch = !setOverflow(&t.inputs, &options)
+	return t
 }
 
-// Start the function wrapped in the start node. This method should be invoked
+// start the function wrapped in the start node. This method should be invoked
 // for all the start nodes of the same pipeline, so the pipeline can properly start and finish.
-func (sn *start[OUT]) Start() {
+func (sn *start[OUT]) start(rs *runState) {
 	// a nil start node can be started without no effect on the pipeline.
 	// this allows setting optional nillable start nodes and let start all of them
 	// as a group in a more convenient way
 	if sn == nil {
 		return
 	}
-	forker, err := sn.receiverGroup.StartReceivers()
+	if sn.running != nil {
+		rewire(rs, sn.name, sn.running.output, sn.running.stats, sn.fanOut, sn.Outs)
+		return
+	}
+	forker, err := startReceivers(rs, sn.stats, sn.fanOut, nil, sn.Outs, sn.feedbackOuts)
 	if err != nil {
-		panic("start: " + err.Error())
+		rs.nodeError(sn.name, err)
+		if forker == nil {
+			return
+		}
 	}
+	forker = switchable(rs, forker, &sn.output)
 
-	go func() {
-		sn.fun(forker.AcquireSender())
+	// each start node has its own context, so it can be stopped when it is removed
+	// from a running pipeline
+	ctx, cancel := context.WithCancel(rs.ctx)
+	sn.cancel = cancel
+	rs.run(func() {
+		out := forker.AcquireSender()
+		rs.runNode(sn.name, sn.panicPolicy, func() error {
+			return sn.fun(ctx, out)
+		})
+		cancel()
 		forker.ReleaseSender()
-	}()
+	})
 }
 
-func (m *middle[IN, OUT]) start() {
-	if len(m.outs) == 0 {
-		panic("middle node should have outputs")
+func (sn *start[OUT]) setName(name string) {
+	if sn != nil {
+		sn.name = name
 	}
+}
+
+func (sn *start[OUT]) nodeName() string {
+	if sn == nil {
+		return ""
+	}
+	return sn.name
+}
+
+func (sn *start[OUT]) kind() nodeKind {
+	return startKind
+}
+
+func (sn *start[OUT]) isNil() bool {
+	return sn == nil
+}
+
+func (sn *start[OUT]) destinations() []pipeNode {
+	if sn == nil {
+		return nil
+	}
+	return asPipeNodes(sn.Outs)
+}
+
+func (sn *start[OUT]) feedbackDestinations() []pipeNode {
+	if sn == nil {
+		return nil
+	}
+	return asPipeNodes(sn.feedbackOuts)
+}
+
+func (sn *start[OUT]) describe() NodeInfo {
+	return NodeInfo{
+		Name: sn.name,
+		Kind: StartKind,
+		Out:  typeOf[OUT](),
+	}
+}
+
+func (sn *start[OUT]) nodeStats() NodeStats {
+	return snapshot(sn.live().stats)
+}
+
+func (m *middle[IN, OUT]) start(rs *runState) {
 	m.started = true
-	joiners := make([]*connect.Joiner[OUT], 0, len(m.outs))
-	for _, out := range m.outs {
-		joiners = append(joiners, out.joiners()...)
-		if !out.isStarted() {
-			out.start()
+	if m.running != nil {
+		rewire(rs, m.name, m.running.output, m.running.stats, m.fanOut, m.outs)
+		return
+	}
+	in := receiver(&m.inputs, m.stats)
+	forker, err := startReceivers(rs, m.stats, m.fanOut, m.loop, m.outs, m.feedbackOuts)
+	if err != nil {
+		rs.nodeError(m.name, err)
+		if forker == nil {
+			go drain(in)
+			return
 		}
 	}
-	forker := connect.Fork(joiners...)
-	go func() {
-		m.fun(m.inputs.Receiver(), forker.AcquireSender())
-		forker.ReleaseSender()
-	}()
+	forker = switchable(rs, forker, &m.output)
+	// all the senders are acquired before running any instance, so the output is
+	// not closed until all of them have returned
+	outs := make([]chan OUT, m.parallelism)
+	for i := range outs {
+		outs[i] = forker.AcquireSender()
+	}
+	running := int32(len(outs))
+	for _, out := range outs {
+		out := out
+		rs.run(func() {
+			rs.runNode(m.name, m.panicPolicy, func() error {
+				// the items in flight won't be consumed if the node returns before its input is closed
+				defer m.loop.Abort()
+				return m.fun(rs.ctx, in, out)
+			})
+			forker.ReleaseSender()
+			// if the last instance returned before its input is closed, we discard the rest
+			// of the input to avoid blocking the sender nodes. The input is not drained while
+			// any other instance is still processing it
+			if atomic.AddInt32(&running, -1) == 0 {
+				go drain(in)
+			}
+		})
+	}
 }
 
-func (t *terminal[IN]) start() {
+func (t *terminal[IN]) start(rs *runState) {
 	if t == nil {
 		return
 	}
 	t.started = true
-	go func() {
-		t.fun(t.inputs.Receiver())
+	if t.running != nil {
+		return
+	}
+	in := receiver(&t.inputs, t.stats)
+	rs.run(func() {
+		rs.runNode(t.name, t.panicPolicy, func() error {
+			return t.fun(rs.ctx, in)
+		})
 		close(t.done)
-	}()
+		go drain(in)
+	})
+}
+
+func asPipeNodes[T any](receivers []Receiver[T]) []pipeNode {
+	nodes := make([]pipeNode, 0, len(receivers))
+	for _, r := range receivers {
+		// the inputs of multi-input nodes are accounted as their owner node
+		if in, ok := r.(*input[T]); ok && in != nil {
+			nodes = append(nodes, in.node)
+			continue
+		}
+		// undefined (nil) receivers are kept, so the graph validation can report them
+		nodes = append(nodes, r)
+	}
+	return nodes
+}
+
+// receiver returns the input channel of a node, instrumented if the node collects stats
+func receiver[IN any](inputs *connect.Joiner[IN], stats *connect.Stats) <-chan IN {
+	if stats != nil {
+		return inputs.InstrumentedReceiver(stats)
+	}
+	return inputs.Receiver()
+}
+
+func drain[T any](in <-chan T) {
+	//nolint:revive
+	for range in {
+	}
 }
 
 func getOptions(opts ...Option) creationOptions {
@@ -219,7 +617,8 @@ func getOptions(opts ...Option) creationOptions {
 // receiverGroup connects a sender node with a collection
 // of Final nodes through a common connect.Forker instance.
 type receiverGroup[OUT any] struct {
-	Outs []Receiver[OUT]
+	Outs         []Receiver[OUT]
+	feedbackOuts []Receiver[OUT]
 }
 
 // SendTo connects a group of receivers to the current receiverGroup
@@ -233,22 +632,50 @@ func (sn *start[OUT]) SendTo(outputs ...Receiver[OUT]) {
 }
 
 func (rg *receiverGroup[OUT]) SendTo(outputs ...Receiver[OUT]) {
-	rg.Outs = append(rg.Outs, outputs...)
+	rg.Outs = appendReceivers(rg.Outs, outputs...)
 }
 
-// StartReceivers start the receivers and return a connection
-// forker to them
-func (rg *receiverGroup[OUT]) StartReceivers() (*connect.Forker[OUT], error) {
-	if len(rg.Outs) == 0 {
-		return nil, errors.New("node should have outputs")
+// FeedbackTo connects a group of receivers to the current receiverGroup, as the
+// destination of a feedback loop.
+func (sn *start[OUT]) FeedbackTo(outputs ...Receiver[OUT]) {
+	if sn != nil {
+		sn.receiverGroup.FeedbackTo(outputs...)
 	}
-	joiners := make([]*connect.Joiner[OUT], 0, len(rg.Outs))
-	for _, out := range rg.Outs {
+}
+
+func (rg *receiverGroup[OUT]) FeedbackTo(outputs ...Receiver[OUT]) {
+	rg.feedbackOuts = appendReceivers(rg.feedbackOuts, outputs...)
+}
+
+// startReceivers start the receivers and return a connection
+// forker to them. If the receivers can be started but not properly
+// connected, it returns an error along with a forker that discards all the data.
+// The loop is the feedback loop of the sender node, if any.
+func startReceivers[OUT any](
+	rs *runState, stats *connect.Stats, fo fanOut, loop *connect.Loop, outs, feedbackOuts []Receiver[OUT],
+) (*connect.Forker[OUT], error) {
+	joiners := make([]*connect.Joiner[OUT], 0, len(outs)+len(feedbackOuts))
+	for _, out := range outs {
 		joiners = append(joiners, out.joiners()...)
 		if !out.isStarted() {
-			out.start()
+			out.start(rs)
+		}
+	}
+	for _, out := range feedbackOuts {
+		joiners = append(joiners, out.feedbackJoiners()...)
+		if !out.isStarted() {
+			out.start(rs)
 		}
 	}
-	forker := connect.Fork(joiners...)
-	return &forker, nil
+	if len(joiners) == 0 {
+		return nil, errors.New("node should have outputs")
+	}
+	forker, err := fork(fo, joiners)
+	if loop != nil {
+		forker.SetLoop(loop)
+	}
+	if stats != nil {
+		forker = connect.Instrument(stats, forker)
+	}
+	return &forker, err
 }
@@ -3,10 +3,27 @@ package pipe
 type creationOptions struct {
 	// if 0, channel is unbuffered
 	channelBufferLen int
+
+	panicPolicy PanicPolicy
+
+	collectStats bool
+
+	parallelism int
+
+	fanOut    FanOutStrategy
+	fanOutKey any
+
+	overflow      OverflowPolicy
+	overflowSpill any
+
+	// pipeline-level options. They are only taken into account when passed to NewBuilder
+	cancelOnError  bool
+	reconfigurable bool
 }
 
 var defaultOptions = creationOptions{
 	channelBufferLen: 0,
+	parallelism:      1,
 }
 
 // Option allows overriding the default properties of the nodes and connections of a pipeline.
@@ -15,8 +32,81 @@ type Option func(options *creationOptions)
 // ChannelBufferLen is an Option that allows specifying the length of the input
 // channels for a given node. The default value is 0, which means that the channels
 // are unbuffered.
+// When the node is one of the multiple destinations of a sender (or it receives data from
+// a sender with a FanOut strategy), the sender can additionally hold one item for the node
+// while it waits for room in the channel, so its siblings keep receiving data. The held items
+// are included in NodeStats.QueueLength and NodeStats.QueueCapacity.
 func ChannelBufferLen(length int) Option {
 	return func(options *creationOptions) {
 		options.channelBufferLen = length
 	}
 }
+
+// CancelOnError is an Option that makes the Runner to cancel the context of the pipeline
+// as soon as any node returns an error, in the same way as Runner.Stop does. This option
+// only has effect when it is passed to the NewBuilder function.
+func CancelOnError() Option {
+	return func(options *creationOptions) {
+		options.cancelOnError = true
+	}
+}
+
+// Reconfigurable is an Option that allows replacing the nodes of the pipeline while it is
+// running, by means of Runner.Reconfigure, and attaching taps to them (see AttachTap). Each Start and Middle node requires an extra
+// goroutine and an extra unbuffered channel to send its data. This option only has effect when
+// it is passed to the NewBuilder function.
+func Reconfigurable() Option {
+	return func(options *creationOptions) {
+		options.reconfigurable = true
+	}
+}
+
+// PanicPolicy specifies how the pipeline behaves when the function of a node panics.
+type PanicPolicy int
+
+const (
+	// PanicCrash does not recover from the panic, so the whole process crashes. It is
+	// the default behavior, as it is in any other goroutine.
+	PanicCrash PanicPolicy = iota
+	// PanicStopPipeline recovers from the panic, reports it to the Runner as a *PanicError, and
+	// cancels the pipeline context in the same way as Runner.Stop does.
+	// The output of the panicking node is closed, and any data pending in its input is discarded.
+	PanicStopPipeline
+	// PanicRestartNode recovers from the panic, reports it to the Runner as a *PanicError, and
+	// invokes again the node function with the same input and output channels. A Start node
+	// is not restarted if the pipeline context has been cancelled.
+	PanicRestartNode
+)
+
+// OnPanic is an Option that specifies the PanicPolicy of a node. If it is passed to the NewBuilder
+// function, it applies to all the nodes in the pipeline.
+func OnPanic(policy PanicPolicy) Option {
+	return func(options *creationOptions) {
+		options.panicPolicy = policy
+	}
+}
+
+// CollectStats is an Option that makes a node count the items that it receives and sends,
+// as well as the time it spends processing them and blocked sending them (see Runner.Stats).
+// If it is passed to the NewBuilder function, it applies to all the nodes in the pipeline.
+// Collecting stats requires an extra goroutine and channel hop for each input and output
+// of the node, so it is disabled by default.
+func CollectStats() Option {
+	return func(options *creationOptions) {
+		options.collectStats = true
+	}
+}
+
+// Parallelism is an Option that makes a Middle node to run n instances of its function
+// in parallel goroutines. All the instances read from the same input channel and write to the
+// same output channel, so the order of the forwarded items is not guaranteed. The output
+// channel is closed when all the instances have returned. If some instances return before the
+// input channel is closed, the rest of the instances keep reading from it.
+// Values lower than 1 are ignored. This option does not have any effect on Start and Final nodes.
+func Parallelism(n int) Option {
+	return func(options *creationOptions) {
+		if n >= 1 {
+			options.parallelism = n
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package pipe
+
+import (
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// OverflowPolicy specifies what the sender nodes do when the input channel of a receiver
+// node is full, so a slow receiver doesn't throttle the rest of the pipeline.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the senders to wait until the input channel of the receiver has
+	// room for the item. It is the default policy.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the items that are sent to a receiver whose input channel is full.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest item in the input channel of the receiver, to
+	// make room for the new item.
+	OverflowDropOldest
+)
+
+// OnOverflow is an Option that specifies the OverflowPolicy of the input channel of a Middle or
+// Final node. If it is passed to the NewBuilder function, it applies to all the nodes in the pipeline.
+// The policies that discard items should be used along with the ChannelBufferLen option. Otherwise,
+// the items are discarded unless the receiver node is waiting for them.
+// The senders that use the LeastLoaded fan-out strategy ignore this option.
+func OnOverflow(policy OverflowPolicy) Option {
+	return func(options *creationOptions) {
+		options.overflow = policy
+		options.overflowSpill = nil
+	}
+}
+
+// OnOverflowSpill is an Option that makes the sender nodes to invoke the spill function with the
+// items that can't be sent to a Middle or Final node because its input channel is full, instead
+// of sending them. The IN type must match the input type of the node (or any of the input types,
+// for multi-input nodes). Otherwise, the Builder returns a *ValidationError listing the node in
+// its MismatchedSpills field.
+// The spill function is invoked from the goroutines of the sender nodes, so it should return fast.
+func OnOverflowSpill[IN any](spill func(IN)) Option {
+	return func(options *creationOptions) {
+		options.overflow = OverflowDropNewest
+		options.overflowSpill = spill
+	}
+}
+
+// connectPolicy returns the equivalent policy of the connect package
+func (p OverflowPolicy) connectPolicy() connect.OverflowPolicy {
+	switch p {
+	case OverflowDropNewest:
+		return connect.DropNewest
+	case OverflowDropOldest:
+		return connect.DropOldest
+	default:
+		return connect.Block
+	}
+}
+
+// setOverflow configures the OverflowPolicy of the joiner from the node options. It returns
+// false if the OnOverflowSpill function does not accept the IN type.
+func setOverflow[IN any](joiner *connect.Joiner[IN], options *creationOptions) bool {
+	if options.overflowSpill == nil {
+		joiner.SetOverflow(options.overflow.connectPolicy(), nil)
+		return true
+	}
+	spill, ok := options.overflowSpill.(func(IN))
+	if !ok {
+		joiner.SetOverflow(connect.DropNewest, nil)
+		return false
+	}
+	joiner.SetOverflow(connect.DropNewest, spill)
+	return true
+}
+
+// spillReceiver nodes can pass the items that overflow their input to an OnOverflowSpill function
+type spillReceiver interface {
+	// spillMatches returns false if the OnOverflowSpill function of the node does not
+	// accept the type of its input
+	spillMatches() bool
+}
+
+func (m *middle[IN, OUT]) spillMatches() bool { return !m.spillMismatch }
+func (t *terminal[IN]) spillMatches() bool    { return !t.spillMismatch }
+func (m *middleDemux[IN]) spillMatches() bool { return !m.spillMismatch }
@@ -0,0 +1,103 @@
+package pipe_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+type debugSinkPipe struct {
+	start pipe.Start[int]
+	prod  pipe.Final[int]
+	debug pipe.Final[int]
+}
+
+func (d *debugSinkPipe) Connect() {
+	d.start.SendTo(d.prod, d.debug)
+}
+
+// runWithSlowSink runs a pipeline where the debug sink does not read any item
+// until the production sink has received all of them
+func runWithSlowSink(t *testing.T, sinkOpts ...pipe.Option) (prod, debug []int, r *pipe.Runner) {
+	p := pipe.NewBuilder(&debugSinkPipe{})
+	pipe.AddStart(p, func(d *debugSinkPipe) *pipe.Start[int] { return &d.start }, Counter(1, 10))
+	prodDone := make(chan struct{})
+	pipe.AddFinal(p, func(d *debugSinkPipe) *pipe.Final[int] { return &d.prod }, func(in <-chan int) {
+		for i := range in {
+			prod = append(prod, i)
+		}
+		close(prodDone)
+	})
+	pipe.AddFinal(p, func(d *debugSinkPipe) *pipe.Final[int] { return &d.debug }, func(in <-chan int) {
+		<-prodDone
+		for i := range in {
+			debug = append(debug, i)
+		}
+	}, sinkOpts...)
+	r, err := p.Build()
+	require.NoError(t, err)
+	r.Start()
+	select {
+	case <-r.Done():
+	case <-time.After(timeout):
+		require.Fail(t, "timeout while waiting for the pipeline to finish")
+	}
+	return prod, debug, r
+}
+
+func TestOverflow_DropNewest(t *testing.T) {
+	prod, debug, r := runWithSlowSink(t,
+		pipe.ChannelBufferLen(2), pipe.OnOverflow(pipe.OverflowDropNewest))
+	require.NoError(t, r.Err())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, prod)
+	assert.Equal(t, []int{1, 2}, debug)
+	assert.Equal(t, uint64(8), r.Stats()["debug"].Dropped)
+	assert.Zero(t, r.Stats()["prod"].Dropped)
+}
+
+func TestOverflow_DropOldest(t *testing.T) {
+	prod, debug, r := runWithSlowSink(t,
+		pipe.ChannelBufferLen(2), pipe.OnOverflow(pipe.OverflowDropOldest))
+	require.NoError(t, r.Err())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, prod)
+	assert.Equal(t, []int{9, 10}, debug)
+	assert.Equal(t, uint64(8), r.Stats()["debug"].Dropped)
+}
+
+func TestOverflow_Spill(t *testing.T) {
+	var mt sync.Mutex
+	var spilled []int
+	prod, debug, r := runWithSlowSink(t, pipe.ChannelBufferLen(3), pipe.OnOverflowSpill(func(i int) {
+		mt.Lock()
+		spilled = append(spilled, i)
+		mt.Unlock()
+	}))
+	require.NoError(t, r.Err())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, prod)
+	assert.Equal(t, []int{1, 2, 3}, debug)
+	assert.Equal(t, []int{4, 5, 6, 7, 8, 9, 10}, spilled)
+}
+
+func TestOverflow_Spill_WrongType(t *testing.T) {
+	p := pipe.NewBuilder(&debugSinkPipe{})
+	pipe.AddStart(p, func(d *debugSinkPipe) *pipe.Start[int] { return &d.start }, Counter(1, 10))
+	pipe.AddFinal(p, func(d *debugSinkPipe) *pipe.Final[int] { return &d.prod }, func(in <-chan int) {
+		for range in {
+		}
+	})
+	pipe.AddFinal(p, func(d *debugSinkPipe) *pipe.Final[int] { return &d.debug }, func(in <-chan int) {
+		for range in {
+		}
+	},
+		pipe.ChannelBufferLen(2), pipe.OnOverflowSpill(func(string) {}))
+	_, err := p.Build()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"debug"}, verr.MismatchedSpills)
+	assert.Contains(t, err.Error(), "OnOverflowSpill functions not matching the input type [debug]")
+}
@@ -0,0 +1,76 @@
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+func TestPanic_StopPipeline(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStartCtx(p, start, infiniteCounter)
+	pipe.AddMiddle(p, mid, func(in <-chan int, out chan<- int) {
+		for i := range in {
+			if i == 3 {
+				panic("three!")
+			}
+			out <- i
+		}
+	}, pipe.OnPanic(pipe.PanicStopPipeline))
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	assert.Equal(t, []int{1, 2}, collected)
+
+	err = r.Err()
+	var nodeErr *pipe.NodeError
+	require.ErrorAs(t, err, &nodeErr)
+	assert.Equal(t, "mid", nodeErr.Node)
+	var panicErr *pipe.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "three!", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+}
+
+func TestPanic_RestartNode(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{}, pipe.OnPanic(pipe.PanicRestartNode))
+	pipe.AddStart(p, start, Counter(1, 6))
+	pipe.AddMiddle(p, mid, func(in <-chan int, out chan<- int) {
+		for i := range in {
+			if i%2 == 1 {
+				panic("odd!")
+			}
+			out <- i
+		}
+	})
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	err = r.Wait()
+	assert.Equal(t, []int{2, 4, 6}, collected)
+
+	require.Error(t, err)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+	var panicErr *pipe.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "odd!", panicErr.Value)
+}
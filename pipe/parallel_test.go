@@ -0,0 +1,100 @@
+package pipe_test
+
+import (
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+func TestParallelism(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStart(p, start, Counter(1, 12))
+	var running, maxRunning int32
+	pipe.AddMiddle(p, mid, func(in <-chan int, out chan<- int) {
+		for i := range in {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				prev := atomic.LoadInt32(&maxRunning)
+				if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			out <- i * 10
+		}
+	}, pipe.Parallelism(4))
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	require.NoError(t, r.Wait())
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&maxRunning))
+	sort.Ints(collected)
+	assert.Equal(t, []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 110, 120}, collected)
+}
+
+func TestParallelism_InstancesReturningEarly(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStart(p, start, Counter(1, 20))
+	// each instance forwards a single item and returns. The output must be
+	// kept open until all the instances have returned.
+	pipe.AddMiddle(p, mid, func(in <-chan int, out chan<- int) {
+		time.Sleep(10 * time.Millisecond)
+		out <- <-in
+	}, pipe.Parallelism(3))
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	require.NoError(t, r.Wait())
+	assert.Len(t, collected, 3)
+}
+
+func TestParallelism_SiblingsKeepInput(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStart(p, start, Counter(1, 20))
+	// the first instance returns immediately. The input is not drained while
+	// the rest of the instances are processing it
+	var instances int32
+	pipe.AddMiddle(p, mid, func(in <-chan int, out chan<- int) {
+		if atomic.AddInt32(&instances, 1) == 1 {
+			return
+		}
+		for i := range in {
+			time.Sleep(time.Millisecond)
+			out <- i
+		}
+	}, pipe.Parallelism(3))
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	require.NoError(t, r.Wait())
+	assert.Len(t, collected, 20)
+}
@@ -0,0 +1,192 @@
+// Package promexport provides an http.Handler that exposes the runtime metrics of
+// one or more pipelines in the Prometheus text exposition format, without depending
+// on the Prometheus client library.
+package promexport
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler renders, for each request, the stats of the registered pipelines (see pipe.Runner.Stats)
+// as the following metrics, labelled by pipeline and node name:
+//   - pipes_items_in_total: counter of the items that have been received by the node
+//   - pipes_items_out_total: counter of the items that have been sent by the node
+//   - pipes_queue_length: number of items waiting in the input channel of the node
+//   - pipes_queue_capacity: length of the buffer of the input channel of the node
+//   - pipes_items_dropped_total: counter of the items that have been discarded because the
+//     input channel of the node was full
+//   - pipes_blocked_seconds_total: time that the node has been blocked sending data
+//   - pipes_node_up: 1 if the node function is running, 0 otherwise
+//
+// The items and blocked time metrics are only exported for the nodes that have been
+// created with the pipe.CollectStats option (see pipe.NodeStats.StatsCollected), so a node that
+// doesn't collect them can be distinguished from an idle node. Start nodes don't export the
+// pipes_items_in_total, queue and dropped items metrics, as they have no input, and Final nodes
+// don't export the pipes_items_out_total and pipes_blocked_seconds_total metrics, as they have
+// no output.
+type Handler struct {
+	mt        sync.Mutex
+	pipelines map[string]*pipe.Runner
+}
+
+// NewHandler creates an empty Handler. Pipelines must be added by means of the Register method.
+func NewHandler() *Handler {
+	return &Handler{pipelines: map[string]*pipe.Runner{}}
+}
+
+// Register adds a pipeline to the Handler, whose metrics will be labelled with the provided
+// pipeline name. Registering a pipeline with an existing name replaces the previous pipeline.
+func (h *Handler) Register(pipeline string, runner *pipe.Runner) {
+	h.mt.Lock()
+	defer h.mt.Unlock()
+	h.pipelines[pipeline] = runner
+}
+
+// Unregister removes the pipeline with the provided name from the Handler.
+func (h *Handler) Unregister(pipeline string) {
+	h.mt.Lock()
+	defer h.mt.Unlock()
+	delete(h.pipelines, pipeline)
+}
+
+type metric struct {
+	name string
+	help string
+	kind string
+	// exported returns whether the metric applies to the node
+	exported func(s *pipe.NodeStats) bool
+	value    func(s *pipe.NodeStats) string
+}
+
+func hasInput(s *pipe.NodeStats) bool {
+	return s.Kind != pipe.StartKind
+}
+
+func collectedIn(s *pipe.NodeStats) bool {
+	return s.StatsCollected && s.Kind != pipe.StartKind
+}
+
+func collectedOut(s *pipe.NodeStats) bool {
+	return s.StatsCollected && s.Kind != pipe.FinalKind
+}
+
+func always(*pipe.NodeStats) bool {
+	return true
+}
+
+var metrics = []metric{{
+	name:     "pipes_items_in_total",
+	help:     "Number of items received by the node.",
+	kind:     "counter",
+	exported: collectedIn,
+	value:    func(s *pipe.NodeStats) string { return strconv.FormatUint(s.ItemsIn, 10) },
+}, {
+	name:     "pipes_items_out_total",
+	help:     "Number of items sent by the node.",
+	kind:     "counter",
+	exported: collectedOut,
+	value:    func(s *pipe.NodeStats) string { return strconv.FormatUint(s.ItemsOut, 10) },
+}, {
+	name:     "pipes_queue_length",
+	help:     "Number of items waiting in the input channel of the node.",
+	kind:     "gauge",
+	exported: hasInput,
+	value:    func(s *pipe.NodeStats) string { return strconv.Itoa(s.QueueLength) },
+}, {
+	name:     "pipes_queue_capacity",
+	help:     "Length of the buffer of the input channel of the node.",
+	kind:     "gauge",
+	exported: hasInput,
+	value:    func(s *pipe.NodeStats) string { return strconv.Itoa(s.QueueCapacity) },
+}, {
+	name:     "pipes_items_dropped_total",
+	help:     "Number of items discarded because the input channel of the node was full.",
+	kind:     "counter",
+	exported: hasInput,
+	value:    func(s *pipe.NodeStats) string { return strconv.FormatUint(s.Dropped, 10) },
+}, {
+	name:     "pipes_blocked_seconds_total",
+	help:     "Time that the node has been blocked sending data to its destinations.",
+	kind:     "counter",
+	exported: collectedOut,
+	value: func(s *pipe.NodeStats) string {
+		return strconv.FormatFloat(s.BlockedTime.Seconds(), 'g', -1, 64)
+	},
+}, {
+	name:     "pipes_node_up",
+	help:     "Whether the function of the node is running (1) or not (0).",
+	kind:     "gauge",
+	exported: always,
+	value: func(s *pipe.NodeStats) string {
+		if s.Running {
+			return "1"
+		}
+		return "0"
+	},
+}}
+
+// nodeStats stores the stats snapshot of a node, with its already escaped labels
+type nodeStats struct {
+	labels string
+	stats  pipe.NodeStats
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	nodes := h.snapshot()
+	rw.Header().Set("Content-Type", contentType)
+	out := bufio.NewWriter(rw)
+	for _, m := range metrics {
+		out.WriteString("# HELP " + m.name + " " + m.help + "\n")
+		out.WriteString("# TYPE " + m.name + " " + m.kind + "\n")
+		for i := range nodes {
+			if !m.exported(&nodes[i].stats) {
+				continue
+			}
+			out.WriteString(m.name + nodes[i].labels + " " + m.value(&nodes[i].stats) + "\n")
+		}
+	}
+	_ = out.Flush()
+}
+
+// snapshot returns the stats of all the nodes, sorted by pipeline and node name
+func (h *Handler) snapshot() []nodeStats {
+	h.mt.Lock()
+	defer h.mt.Unlock()
+	pipelines := make([]string, 0, len(h.pipelines))
+	for name := range h.pipelines {
+		pipelines = append(pipelines, name)
+	}
+	sort.Strings(pipelines)
+	var nodes []nodeStats
+	for _, pipeline := range pipelines {
+		stats := h.pipelines[pipeline].Stats()
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			nodes = append(nodes, nodeStats{
+				labels: `{pipeline="` + escape(pipeline) + `",node="` + escape(name) + `"}`,
+				stats:  stats[name],
+			})
+		}
+	}
+	return nodes
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape a label value as specified by the Prometheus text format
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
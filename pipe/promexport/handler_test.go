@@ -0,0 +1,98 @@
+package promexport_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/mariomac/pipes/pipe/promexport"
+)
+
+type testPipe struct {
+	numbers pipe.Start[int]
+	printer pipe.Final[int]
+}
+
+func (t *testPipe) Connect() {
+	t.numbers.SendTo(t.printer)
+}
+
+func runPipe(t *testing.T, items int, opts ...pipe.Option) *pipe.Runner {
+	b := pipe.NewBuilder(&testPipe{}, opts...)
+	pipe.AddStart(b, func(t *testPipe) *pipe.Start[int] { return &t.numbers },
+		func(out chan<- int) {
+			for i := 0; i < items; i++ {
+				out <- i
+			}
+		})
+	pipe.AddFinal(b, func(t *testPipe) *pipe.Final[int] { return &t.printer },
+		func(in <-chan int) {
+			for range in {
+			}
+		}, pipe.ChannelBufferLen(5))
+	r, err := b.Build()
+	require.NoError(t, err)
+	r.Start()
+	require.NoError(t, r.Wait())
+	return r
+}
+
+func TestHandler(t *testing.T) {
+	h := promexport.NewHandler()
+	h.Register("second", runPipe(t, 3, pipe.CollectStats()))
+	h.Register(`fir"st`, runPipe(t, 2, pipe.CollectStats()))
+	h.Register("uncollected", runPipe(t, 4))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `# HELP pipes_items_in_total Number of items received by the node.
+# TYPE pipes_items_in_total counter
+pipes_items_in_total{pipeline="fir\"st",node="printer"} 2
+pipes_items_in_total{pipeline="second",node="printer"} 3
+# HELP pipes_items_out_total`)
+	assert.Contains(t, string(body), `pipes_items_out_total{pipeline="second",node="numbers"} 3
+`)
+	assert.Contains(t, string(body), `# TYPE pipes_queue_length gauge
+`)
+	assert.Contains(t, string(body), `pipes_queue_capacity{pipeline="second",node="printer"} 5
+`)
+	assert.Contains(t, string(body), `# TYPE pipes_blocked_seconds_total counter
+`)
+	assert.Contains(t, string(body), `pipes_items_dropped_total{pipeline="second",node="printer"} 0
+`)
+	assert.Contains(t, string(body), `pipes_node_up{pipeline="second",node="printer"} 0
+`)
+	// start nodes have no input, and final nodes have no output
+	assert.NotContains(t, string(body), `pipes_items_in_total{pipeline="second",node="numbers"}`)
+	assert.NotContains(t, string(body), `pipes_queue_length{pipeline="second",node="numbers"}`)
+	assert.NotContains(t, string(body), `pipes_queue_capacity{pipeline="second",node="numbers"}`)
+	assert.NotContains(t, string(body), `pipes_items_out_total{pipeline="second",node="printer"}`)
+	assert.NotContains(t, string(body), `pipes_blocked_seconds_total{pipeline="second",node="printer"}`)
+	// the metrics that are not collected are not exported
+	assert.NotContains(t, string(body), `pipes_items_in_total{pipeline="uncollected"`)
+	assert.NotContains(t, string(body), `pipes_items_out_total{pipeline="uncollected"`)
+	assert.NotContains(t, string(body), `pipes_blocked_seconds_total{pipeline="uncollected"`)
+	assert.Contains(t, string(body), `pipes_queue_capacity{pipeline="uncollected",node="printer"} 5
+`)
+	assert.Contains(t, string(body), `pipes_node_up{pipeline="uncollected",node="numbers"} 0
+`)
+
+	h.Unregister("second")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.NotContains(t, rec.Body.String(), `pipeline="second"`)
+	assert.Contains(t, rec.Body.String(), `pipeline="fir\"st"`)
+}
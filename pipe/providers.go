@@ -26,6 +26,16 @@ type NodesMap interface {
 	Connect()
 }
 
+// NamedNodesMap is a NodesMap that provides the names of its nodes. It allows naming the nodes
+// of NodesMap implementations that aren't structs (e.g. nodes that are defined at runtime from a
+// configuration file). Otherwise, the nodes are named after the fields of the NodesMap struct.
+type NamedNodesMap interface {
+	NodesMap
+	// NodeNames returns the name of each node, indexed by the pointer that is returned
+	// for that node by the StartPtr, MiddlePtr or FinalPtr functions.
+	NodeNames() map[any]string
+}
+
 // StartPtr is a function that, given a NodesMap, returns a pointer to a
 // Start node, which is going to be used as store destination
 // when this function is passed as argument to AddStartProvider
@@ -100,24 +110,91 @@ type MiddleProvider[IN, OUT any] func() (MiddleFunc[IN, OUT], error)
 //	return IgnoreFinal[T](), nil
 type FinalProvider[IN any] func() (FinalFunc[IN], error)
 
+// StartProviderCtx is a StartProvider that returns a StartFuncCtx.
+type StartProviderCtx[OUT any] func() (StartFuncCtx[OUT], error)
+
+// MiddleProviderCtx is a MiddleProvider that returns a MiddleFuncCtx.
+type MiddleProviderCtx[IN, OUT any] func() (MiddleFuncCtx[IN, OUT], error)
+
+// FinalProviderCtx is a FinalProvider that returns a FinalFuncCtx.
+type FinalProviderCtx[IN any] func() (FinalFuncCtx[IN], error)
+
+// StartProviderErr is a StartProvider that returns a StartFuncErr.
+type StartProviderErr[OUT any] func() (StartFuncErr[OUT], error)
+
+// MiddleProviderErr is a MiddleProvider that returns a MiddleFuncErr.
+type MiddleProviderErr[IN, OUT any] func() (MiddleFuncErr[IN, OUT], error)
+
+// FinalProviderErr is a FinalProvider that returns a FinalFuncErr.
+type FinalProviderErr[IN any] func() (FinalFuncErr[IN], error)
+
 // AddStartProvider registers a StartProviderFunc into the pipeline Builder.
 // The function returned by the StartProvider will be assigned to the NodesMap
 // field whose pointer is returned by the passed StartPtr function.
-func AddStartProvider[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], provider StartProvider[OUT]) {
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartProvider[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], provider StartProvider[OUT], opts ...Option) {
+	addStartProvider(p, field, asStart[OUT], provider, opts...)
+}
+
+// AddStartProviderCtx registers a StartProviderCtx into the pipeline Builder.
+// The function returned by the StartProviderCtx will be assigned to the NodesMap
+// field whose pointer is returned by the passed StartPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartProviderCtx[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], provider StartProviderCtx[OUT], opts ...Option) {
+	addStartProvider(p, field, asStartCtx[OUT], provider, opts...)
+}
+
+// AddStartProviderErr registers a StartProviderErr into the pipeline Builder.
+// The function returned by the StartProviderErr will be assigned to the NodesMap
+// field whose pointer is returned by the passed StartPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartProviderErr[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], provider StartProviderErr[OUT], opts ...Option) {
+	addStartProvider(p, field, asStartErr[OUT], provider, opts...)
+}
+
+func addStartProvider[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], asNode, provider any, opts ...Option) {
 	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
 	p.startNodes[dstAddress] = nodeOrProvider[startable]{
 		provider: &reflectProvider{
 			acceptNilFunc: true,
-			asNode:        reflect.ValueOf(asStart[OUT]),
+			asNode:        reflect.ValueOf(asNode),
 			fieldGetter:   reflect.ValueOf(field),
 			fn:            reflect.ValueOf(provider),
+			opts:          p.joinOpts(opts...),
 		}}
 }
 
 // AddMiddleProvider registers a MiddleProvider into the pipeline Builder.
 // The function returned by the MiddleProvider will be assigned to the NodesMap
 // field whose pointer is returned by the passed MiddlePtr function.
-func AddMiddleProvider[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], provider MiddleProvider[IN, OUT]) {
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddMiddleProvider[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], provider MiddleProvider[IN, OUT], opts ...Option) {
+	addMiddleProvider(p, field, asMiddle[IN, OUT], provider, opts...)
+}
+
+// AddMiddleProviderCtx registers a MiddleProviderCtx into the pipeline Builder.
+// The function returned by the MiddleProviderCtx will be assigned to the NodesMap
+// field whose pointer is returned by the passed MiddlePtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddMiddleProviderCtx[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], provider MiddleProviderCtx[IN, OUT], opts ...Option) {
+	addMiddleProvider(p, field, asMiddleCtx[IN, OUT], provider, opts...)
+}
+
+// AddMiddleProviderErr registers a MiddleProviderErr into the pipeline Builder.
+// The function returned by the MiddleProviderErr will be assigned to the NodesMap
+// field whose pointer is returned by the passed MiddlePtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddMiddleProviderErr[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], provider MiddleProviderErr[IN, OUT], opts ...Option) {
+	addMiddleProvider(p, field, asMiddleErr[IN, OUT], provider, opts...)
+}
+
+func addMiddleProvider[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], asNode, provider any, opts ...Option) {
 	var i IN
 	var o OUT
 	// middle providers where IN & OUT are the same type can be bypassed if they return
@@ -128,34 +205,83 @@ func AddMiddleProvider[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field Middl
 		bypassableNode = &rv
 	}
 	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
-	p.middleNodes[dstAddress] = nodeOrProvider[struct{}]{
+	p.middleNodes[dstAddress] = nodeOrProvider[pipeNode]{
 		provider: &reflectProvider{
 			middleBypasser: bypassableNode,
-			asNode:         reflect.ValueOf(asMiddle[IN, OUT]),
+			asNode:         reflect.ValueOf(asNode),
 			fieldGetter:    reflect.ValueOf(field),
 			fn:             reflect.ValueOf(provider),
+			opts:           p.joinOpts(opts...),
 		}}
 }
 
 // AddFinalProvider registers a FinalProvider into the pipeline Builder.
 // The function returned by the FinalProvider will be assigned to the NodesMap
 // field whose pointer is returned by the passed FinalPtr function.
-func AddFinalProvider[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], provider FinalProvider[IN]) {
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinalProvider[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], provider FinalProvider[IN], opts ...Option) {
+	addFinalProvider(p, field, asFinal[IN], provider, opts...)
+}
+
+// AddFinalProviderCtx registers a FinalProviderCtx into the pipeline Builder.
+// The function returned by the FinalProviderCtx will be assigned to the NodesMap
+// field whose pointer is returned by the passed FinalPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinalProviderCtx[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], provider FinalProviderCtx[IN], opts ...Option) {
+	addFinalProvider(p, field, asFinalCtx[IN], provider, opts...)
+}
+
+// AddFinalProviderErr registers a FinalProviderErr into the pipeline Builder.
+// The function returned by the FinalProviderErr will be assigned to the NodesMap
+// field whose pointer is returned by the passed FinalPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddFinalProviderErr[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], provider FinalProviderErr[IN], opts ...Option) {
+	addFinalProvider(p, field, asFinalErr[IN], provider, opts...)
+}
+
+func addFinalProvider[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], asNode, provider any, opts ...Option) {
 	dstAddress := reflect.ValueOf(field(p.nodesMap)).Pointer()
 	p.finalNodes[dstAddress] = nodeOrProvider[doneable]{
 		provider: &reflectProvider{
 			acceptNilFunc: true,
-			asNode:        reflect.ValueOf(asFinal[IN]),
+			asNode:        reflect.ValueOf(asNode),
 			fieldGetter:   reflect.ValueOf(field),
 			fn:            reflect.ValueOf(provider),
+			opts:          p.joinOpts(opts...),
 		}}
 }
 
 // AddStart creates a Start node given the provided StartFunc. The node will
 // be assigned to the field of the NodesMap whose pointer is returned by the
 // provided StartPtr function.
-func AddStart[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], fn StartFunc[OUT]) {
-	startNode := asStart(fn)
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStart[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], fn StartFunc[OUT], opts ...Option) {
+	addStart(p, field, asStart(fn, p.joinOpts(opts...)...))
+}
+
+// AddStartCtx creates a Start node given the provided StartFuncCtx. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided StartPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartCtx[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], fn StartFuncCtx[OUT], opts ...Option) {
+	addStart(p, field, asStartCtx(fn, p.joinOpts(opts...)...))
+}
+
+// AddStartErr creates a Start node given the provided StartFuncErr. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided StartPtr function.
+// The options of that node can be overridden. Otherwise the global options
+// passed to the pipeline Builder are used.
+func AddStartErr[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], fn StartFuncErr[OUT], opts ...Option) {
+	addStart(p, field, asStartErr(fn, p.joinOpts(opts...)...))
+}
+
+func addStart[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT], startNode *start[OUT]) {
 	dstAddress := field(p.nodesMap)
 	p.startNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[startable]{node: startNode}
 	*(dstAddress) = startNode
@@ -167,9 +293,31 @@ func AddStart[IMPL NodesMap, OUT any](p *Builder[IMPL], field StartPtr[IMPL, OUT
 // The options related to the connection to that Middle node can be overridden. Otherwise
 // the global options passed to the pipeline Builder are used.
 func AddMiddle[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], fn MiddleFunc[IN, OUT], opts ...Option) {
+	addMiddle(p, field, asMiddle(fn, p.joinOpts(opts...)...))
+}
+
+// AddMiddleCtx creates a Middle node given the provided MiddleFuncCtx. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided MiddlePtr function.
+// The options related to the connection to that Middle node can be overridden. Otherwise
+// the global options passed to the pipeline Builder are used.
+func AddMiddleCtx[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], fn MiddleFuncCtx[IN, OUT], opts ...Option) {
+	addMiddle(p, field, asMiddleCtx(fn, p.joinOpts(opts...)...))
+}
+
+// AddMiddleErr creates a Middle node given the provided MiddleFuncErr. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided MiddlePtr function.
+// The options related to the connection to that Middle node can be overridden. Otherwise
+// the global options passed to the pipeline Builder are used.
+func AddMiddleErr[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], fn MiddleFuncErr[IN, OUT], opts ...Option) {
+	addMiddle(p, field, asMiddleErr(fn, p.joinOpts(opts...)...))
+}
+
+func addMiddle[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMPL, IN, OUT], middleNode *middle[IN, OUT]) {
 	dstAddress := field(p.nodesMap)
-	p.middleNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[struct{}]{}
-	*(dstAddress) = asMiddle(fn, p.joinOpts(opts...)...)
+	p.middleNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[pipeNode]{node: middleNode}
+	*(dstAddress) = middleNode
 }
 
 // AddFinal creates a Final node given the provided FinalFunc. The node will
@@ -178,7 +326,28 @@ func AddMiddle[IMPL NodesMap, IN, OUT any](p *Builder[IMPL], field MiddlePtr[IMP
 // The options related to the connection to that Final node can be overridden. Otherwise
 // the global options passed to the pipeline Builder are used.
 func AddFinal[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], fn FinalFunc[IN], opts ...Option) {
-	termNode := asFinal(fn, p.joinOpts(opts...)...)
+	addFinal(p, field, asFinal(fn, p.joinOpts(opts...)...))
+}
+
+// AddFinalCtx creates a Final node given the provided FinalFuncCtx. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided FinalPtr function.
+// The options related to the connection to that Final node can be overridden. Otherwise
+// the global options passed to the pipeline Builder are used.
+func AddFinalCtx[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], fn FinalFuncCtx[IN], opts ...Option) {
+	addFinal(p, field, asFinalCtx(fn, p.joinOpts(opts...)...))
+}
+
+// AddFinalErr creates a Final node given the provided FinalFuncErr. The node will
+// be assigned to the field of the NodesMap whose pointer is returned by the
+// provided FinalPtr function.
+// The options related to the connection to that Final node can be overridden. Otherwise
+// the global options passed to the pipeline Builder are used.
+func AddFinalErr[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], fn FinalFuncErr[IN], opts ...Option) {
+	addFinal(p, field, asFinalErr(fn, p.joinOpts(opts...)...))
+}
+
+func addFinal[IMPL NodesMap, IN any](p *Builder[IMPL], field FinalPtr[IMPL, IN], termNode *terminal[IN]) {
 	dstAddress := field(p.nodesMap)
 	p.finalNodes[reflect.ValueOf(dstAddress).Pointer()] = nodeOrProvider[doneable]{node: termNode}
 	*(dstAddress) = termNode
@@ -0,0 +1,252 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// reconfigurable nodes can be kept, added or removed from a running pipeline (see Runner.Reconfigure)
+type reconfigurable interface {
+	pipeNode
+	// adopt the running function of the previous node with the same name, if both nodes are of
+	// the same type. The input of the running node, if any, is held until the release function
+	// is invoked, so it is not closed while its senders are reconnected.
+	adopt(previous pipeNode) (release func(), ok bool)
+}
+
+// stoppable nodes need to be stopped when they are removed from a running pipeline. The rest of
+// nodes finish when their inputs are closed.
+type stoppable interface {
+	stop()
+}
+
+// Reconfigure replaces, while the pipeline is running, its nodes and connections by the nodes and
+// connections of the next Runner, which must have been built but not started. The running
+// pipeline must have been built with the Reconfigurable option.
+//
+// The nodes of both pipelines are matched by name (by default, the name of their NodesMap field):
+//   - The nodes that only exist in the running pipeline are removed. The context of the removed
+//     Start nodes is cancelled, and the removed Middle and Final nodes are stopped once all their
+//     senders stop sending data to them.
+//   - The nodes that only exist in the next pipeline are started.
+//   - The nodes that exist in both pipelines with the same type are kept: the running function
+//     keeps processing data, with its state and its input channel, and the function of the next
+//     node is discarded, as well as its options (except the FanOut strategy, which applies to
+//     the new connections). The nodes whose names are passed in the replace argument, and the
+//     Middle and Final nodes whose input has already been closed, are removed and started again.
+//   - The kept Start and Middle nodes are connected to their destinations in the next pipeline.
+//
+// The reconnection of the nodes neither loses nor duplicates items: each item that is sent by a kept
+// node is delivered either to its previous or to its next destinations, and the removed Middle and
+// Final nodes keep processing the input from their previous senders until all of them have finished
+// or have been reconnected. However, a kept node might receive items from a removed node after
+// receiving items from its new senders, so the order of the items is only preserved between nodes
+// that remain connected. This guarantee does not cover the following cases, where items can be lost:
+//   - The function of a removed or kept node returns before its input is closed (e.g. because of
+//     an error, or a panic that is recovered by the PanicStopPipeline policy). The rest of its input
+//     is discarded, as it is in any pipeline.
+//   - A removed Start node has been defined as StartFunc. As in Stop, it can't be interrupted, so it
+//     keeps sending data to its previous destinations, which are not stopped until it returns.
+//   - The receivers discard items according to their OverflowPolicy.
+//
+// Pipelines with feedback loops, demux nodes or multi-input nodes can't be reconfigured.
+// The next pipeline is validated again, and Reconfigure returns a *ValidationError if its nodes
+// can't properly run. If Reconfigure returns an error, the running pipeline is not modified.
+// After a successful reconfiguration, the Graph and Stats of the Runner describe the next pipeline
+// (removed nodes are not described, despite they might be still finishing), and the Done channel
+// is closed when the kept, started and removed nodes have finished. The next Runner must not be
+// used anymore.
+func (b *Runner) Reconfigure(next *Runner, replace ...string) error {
+	b.reconfigureMt.Lock()
+	defer b.reconfigureMt.Unlock()
+	rs := b.state
+	switch {
+	case !rs.reconfigurable:
+		return errors.New("the pipeline must be built with the Reconfigurable option")
+	case !rs.started():
+		return errors.New("the pipeline has not been started")
+	case next == b || next.state.started():
+		return errors.New("the next pipeline can't be already started")
+	}
+	if err := checkReconfigurable(b.nodes); err != nil {
+		return err
+	}
+	if err := checkReconfigurable(next.nodes); err != nil {
+		return err
+	}
+	// the next pipeline has been validated by the Builder, but its nodes could have been connected
+	// afterwards. Any error that would be reported when starting them is returned before adopting
+	// the running nodes
+	if err := validate(next.nodes); err != nil {
+		return err
+	}
+	// the Done channel can't be closed while the next nodes are started
+	if !rs.running.addRunning() {
+		return errors.New("the pipeline has already finished")
+	}
+	defer rs.running.done()
+
+	replaced := map[string]bool{}
+	for _, name := range replace {
+		replaced[name] = true
+	}
+	removed := map[string]pipeNode{}
+	for _, n := range b.nodes {
+		if !n.isNil() {
+			removed[n.nodeName()] = n
+		}
+	}
+	var holds []func()
+	for _, n := range next.nodes {
+		if n.isNil() || replaced[n.nodeName()] {
+			continue
+		}
+		if previous, ok := removed[n.nodeName()]; ok {
+			if release, ok := n.(reconfigurable).adopt(previous); ok {
+				holds = append(holds, release)
+				delete(removed, n.nodeName())
+			}
+		}
+	}
+
+	// starting the start nodes of the next pipeline starts the added nodes and reconnects
+	// the kept nodes. As in StartCtx, the added nodes don't run until all of them are connected
+	rs.ready = make(chan struct{})
+	for _, s := range next.startNodes {
+		s.start(rs)
+	}
+	for _, n := range removed {
+		if s, ok := n.(stoppable); ok {
+			s.stop()
+		}
+	}
+	close(rs.ready)
+	for _, release := range holds {
+		release()
+	}
+
+	b.nodesMt.Lock()
+	b.startNodes, b.finalNodes, b.nodes = next.startNodes, next.finalNodes, next.nodes
+	b.nodesMt.Unlock()
+	return nil
+}
+
+// checkReconfigurable returns an error if any node of the pipeline can't be reconfigured
+func checkReconfigurable(nodes []pipeNode) error {
+	for _, n := range nodes {
+		if n.isNil() {
+			continue
+		}
+		if _, ok := n.(reconfigurable); !ok {
+			return fmt.Errorf("node %s: demux and multi-input nodes can't be reconfigured", n.nodeName())
+		}
+		if len(n.feedbackDestinations()) > 0 {
+			return fmt.Errorf("node %s: feedback loops can't be reconfigured", n.nodeName())
+		}
+	}
+	return nil
+}
+
+// switchable wraps the forker of a node into a connect.Switch, if the pipeline is reconfigurable
+func switchable[OUT any](rs *runState, forker *connect.Forker[OUT], output **connect.Switch[OUT]) *connect.Forker[OUT] {
+	if !rs.reconfigurable {
+		return forker
+	}
+	sw, switched := connect.NewSwitch(*forker)
+	*output = sw
+	return &switched
+}
+
+// rewire connects the output of a running node to the provided receivers, starting them if needed
+func rewire[OUT any](
+	rs *runState, name string, output *connect.Switch[OUT], stats *connect.Stats, fo fanOut, outs []Receiver[OUT],
+) {
+	// the receivers have been validated by Reconfigure, so no error is expected here
+	forker, err := startReceivers(rs, stats, fo, nil, outs, nil)
+	if err != nil {
+		rs.nodeError(name, err)
+		if forker == nil {
+			return
+		}
+	}
+	if !output.Replace(*forker) {
+		// the node has already finished, so the receivers are released as soon as they are ready
+		rs.run(func() {
+			forker.AcquireSender()
+			forker.ReleaseSender()
+		})
+	}
+}
+
+func noRelease() {}
+
+// live returns the node that runs the function: the node itself or, if the node replaced
+// another node in a running pipeline, the replaced node.
+func (sn *start[OUT]) live() *start[OUT] {
+	if sn.running != nil {
+		return sn.running
+	}
+	return sn
+}
+
+func (sn *start[OUT]) adopt(previous pipeNode) (func(), bool) {
+	p, ok := previous.(*start[OUT])
+	if !ok || p == nil {
+		return nil, false
+	}
+	sn.running = p.live()
+	return noRelease, true
+}
+
+func (sn *start[OUT]) stop() {
+	if live := sn.live(); live.cancel != nil {
+		live.cancel()
+	}
+}
+
+func (m *middle[IN, OUT]) live() *middle[IN, OUT] {
+	if m.running != nil {
+		return m.running
+	}
+	return m
+}
+
+func (m *middle[IN, OUT]) adopt(previous pipeNode) (func(), bool) {
+	p, ok := previous.(*middle[IN, OUT])
+	if !ok {
+		return nil, false
+	}
+	live := p.live()
+	if _, open := live.inputs.TryAcquireSender(); !open {
+		return nil, false
+	}
+	m.running = live
+	return live.inputs.ReleaseSender, true
+}
+
+func (t *terminal[IN]) live() *terminal[IN] {
+	if t.running != nil {
+		return t.running
+	}
+	return t
+}
+
+func (t *terminal[IN]) adopt(previous pipeNode) (func(), bool) {
+	p, ok := previous.(*terminal[IN])
+	if !ok || p == nil {
+		return nil, false
+	}
+	live := p.live()
+	if _, open := live.inputs.TryAcquireSender(); !open {
+		return nil, false
+	}
+	t.running = live
+	return live.inputs.ReleaseSender, true
+}
+
+// bypass nodes don't run any function, so they are always replaced
+func (b *bypass[INOUT]) adopt(pipeNode) (func(), bool) {
+	return nil, false
+}
@@ -0,0 +1,198 @@
+package pipe_test
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+type reconfigurablePipe struct {
+	Source pipe.Start[int]
+	Filter pipe.Middle[int, int]
+	Sink   pipe.Final[int]
+	Audit  pipe.Final[int]
+}
+
+func (p *reconfigurablePipe) Connect() {
+	p.Source.SendTo(p.Filter)
+	p.Filter.SendTo(p.Sink, p.Audit)
+}
+
+func forward(dst chan<- int) pipe.FinalFunc[int] {
+	return func(in <-chan int) {
+		for i := range in {
+			dst <- i
+		}
+	}
+}
+
+func TestRunner_Reconfigure(t *testing.T) {
+	input := make(chan int)
+	sources := int32(0)
+	source := func(_ context.Context, out chan<- int) {
+		atomic.AddInt32(&sources, 1)
+		for i := range input {
+			out <- i
+		}
+	}
+	sunk, audited := make(chan int, 10), make(chan int, 10)
+
+	p1 := pipe.NewBuilder(&reconfigurablePipe{}, pipe.Reconfigurable())
+	pipe.AddStartCtx(p1, func(p *reconfigurablePipe) *pipe.Start[int] { return &p.Source }, source)
+	pipe.AddMiddle(p1, func(p *reconfigurablePipe) *pipe.Middle[int, int] { return &p.Filter }, EvenFilter)
+	pipe.AddFinal(p1, func(p *reconfigurablePipe) *pipe.Final[int] { return &p.Sink }, forward(sunk))
+	pipe.AddFinalProvider(p1, func(p *reconfigurablePipe) *pipe.Final[int] { return &p.Audit }, func() (pipe.FinalFunc[int], error) {
+		return pipe.IgnoreFinal[int](), nil
+	})
+	r, err := p1.Build()
+	require.NoError(t, err)
+	r.Start()
+
+	for i := 1; i <= 4; i++ {
+		input <- i
+	}
+	assert.Equal(t, 2, helpers.ReadChannel(t, sunk, timeout))
+	assert.Equal(t, 4, helpers.ReadChannel(t, sunk, timeout))
+
+	// replacing the filter and adding an audit node
+	p2 := pipe.NewBuilder(&reconfigurablePipe{})
+	pipe.AddStartCtx(p2, func(p *reconfigurablePipe) *pipe.Start[int] { return &p.Source }, source)
+	pipe.AddMiddle(p2, func(p *reconfigurablePipe) *pipe.Middle[int, int] { return &p.Filter }, OddFilter)
+	pipe.AddFinal(p2, func(p *reconfigurablePipe) *pipe.Final[int] { return &p.Sink }, forward(sunk))
+	pipe.AddFinal(p2, func(p *reconfigurablePipe) *pipe.Final[int] { return &p.Audit }, forward(audited))
+	next, err := p2.Build()
+	require.NoError(t, err)
+	require.NoError(t, r.Reconfigure(next, "Filter"))
+
+	for i := 5; i <= 8; i++ {
+		input <- i
+	}
+	assert.Equal(t, 5, helpers.ReadChannel(t, sunk, timeout))
+	assert.Equal(t, 7, helpers.ReadChannel(t, sunk, timeout))
+	assert.Equal(t, 5, helpers.ReadChannel(t, audited, timeout))
+	assert.Equal(t, 7, helpers.ReadChannel(t, audited, timeout))
+
+	// the runner describes the new pipeline
+	var names []string
+	for _, n := range r.Graph().Nodes {
+		names = append(names, n.Name)
+	}
+	assert.Equal(t, []string{"Source", "Filter", "Sink", "Audit"}, names)
+
+	// the start node hasn't been restarted, and the runner finishes when it returns
+	close(input)
+	helpers.ReadChannel(t, r.Done(), timeout)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&sources))
+	require.NoError(t, r.Err())
+}
+
+type passPipe struct {
+	Source pipe.Start[int]
+	Pass   pipe.Middle[int, int]
+	Sink   pipe.Final[int]
+}
+
+func (p *passPipe) Connect() {
+	p.Source.SendTo(p.Pass)
+	p.Pass.SendTo(p.Sink)
+}
+
+func TestRunner_Reconfigure_NoLossNorDuplicates(t *testing.T) {
+	var received []int
+	build := func() *pipe.Runner {
+		p := pipe.NewBuilder(&passPipe{}, pipe.Reconfigurable(), pipe.ChannelBufferLen(10))
+		pipe.AddStartCtx(p, func(p *passPipe) *pipe.Start[int] { return &p.Source }, infiniteCounter)
+		pipe.AddMiddle(p, func(p *passPipe) *pipe.Middle[int, int] { return &p.Pass }, func(in <-chan int, out chan<- int) {
+			for i := range in {
+				out <- i
+			}
+		})
+		pipe.AddFinal(p, func(p *passPipe) *pipe.Final[int] { return &p.Sink }, func(in <-chan int) {
+			for i := range in {
+				received = append(received, i)
+			}
+		})
+		r, err := p.Build()
+		require.NoError(t, err)
+		return r
+	}
+
+	r := build()
+	r.Start()
+	// replacing the middle node while the start node keeps producing
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Millisecond)
+		require.NoError(t, r.Reconfigure(build(), "Pass"))
+	}
+	r.Stop()
+	helpers.ReadChannel(t, r.Done(), timeout)
+
+	// the items from the removed nodes might arrive after the items from the new nodes
+	sort.Ints(received)
+	require.NotEmpty(t, received)
+	for i, n := range received {
+		require.Equal(t, i+1, n)
+	}
+}
+
+func TestRunner_Reconfigure_Errors(t *testing.T) {
+	build := func(opts ...pipe.Option) *pipe.Runner {
+		p := pipe.NewBuilder(&smfPipe{}, opts...)
+		pipe.AddStart(p, start, Counter(1, 3))
+		pipe.AddMiddle(p, mid, EvenFilter)
+		pipe.AddFinal(p, final, func(in <-chan int) {
+			for range in {
+			}
+		})
+		r, err := p.Build()
+		require.NoError(t, err)
+		return r
+	}
+
+	// the pipeline must be reconfigurable
+	r := build()
+	r.Start()
+	require.Error(t, r.Reconfigure(build()))
+	helpers.ReadChannel(t, r.Done(), timeout)
+
+	// the pipeline must be running
+	r = build(pipe.Reconfigurable())
+	require.Error(t, r.Reconfigure(build()))
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	require.Error(t, r.Reconfigure(build()))
+
+	// the next pipeline can't be started
+	next := build()
+	next.Start()
+	require.Error(t, build(pipe.Reconfigurable()).Reconfigure(next))
+
+	// the next pipeline is validated again, as its nodes could have been connected after building it
+	nodes := &smfPipe{}
+	p := pipe.NewBuilder(nodes)
+	pipe.AddStart(p, start, Counter(1, 3))
+	pipe.AddMiddle(p, mid, EvenFilter)
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for range in {
+		}
+	})
+	next, err := p.Build()
+	require.NoError(t, err)
+	nodes.mid.SendTo(nodes.mid)
+	r = build(pipe.Reconfigurable())
+	r.Start()
+	var verr *pipe.ValidationError
+	require.ErrorAs(t, r.Reconfigure(next), &verr)
+	assert.Equal(t, []string{"mid"}, verr.SelfLoops)
+	// the running pipeline has not been modified
+	helpers.ReadChannel(t, r.Done(), timeout)
+	require.NoError(t, r.Err())
+}
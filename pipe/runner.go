@@ -1,5 +1,12 @@
 package pipe
 
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+)
+
 // Runner stores all the configured nodes of a pipeline once their nodes
 // are instantiated (as specified by AddStart, AddStartProvider,
 // AddMiddle, AddMiddleProvider, AddFinal, AddFinalProvider) and connected
@@ -12,25 +19,273 @@ type Runner struct {
 	// tha last change will prevail, without leaving lost startnodes around there
 	startNodes map[uintptr]startable
 	finalNodes map[uintptr]doneable
+	// all the nodes of the pipeline, including the start and final nodes
+	nodes []pipeNode
+	// nodesMt protects the above fields, which are replaced by Reconfigure
+	nodesMt sync.RWMutex
+	// reconfigureMt serializes the invocations to Reconfigure
+	reconfigureMt sync.Mutex
+
+	state    *runState
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
-// Start the pipeline processing in a background.
+// Start the pipeline processing in a background. It is equivalent to
+// invoking StartCtx with a background context.
 func (b *Runner) Start() {
+	b.StartCtx(context.Background())
+}
+
+// StartCtx starts the pipeline processing in a background. The passed context
+// is forwarded to the nodes that have been defined by means of the *Ctx
+// function variants (e.g. StartFuncCtx).
+// Cancelling the context (or invoking Stop) makes the start nodes return. Then,
+// the rest of the nodes will keep processing the data that is still in the pipeline
+// until their input channels are closed.
+// Start nodes that have been defined as StartFunc can't be interrupted, as they
+// don't receive any context. You should use StartFuncCtx instead.
+// A Runner can only be started once: invoking Start or StartCtx again has no effect.
+func (b *Runner) StartCtx(ctx context.Context) {
+	rs := b.state
+	rs.startMt.Lock()
+	defer rs.startMt.Unlock()
+	if rs.ctx != nil {
+		return
+	}
+	rs.ctx, rs.cancel = context.WithCancel(ctx)
+	if rs.stopped {
+		rs.cancel()
+	}
+	// make sure that the Done channel is not closed until all the nodes are started
+	rs.running.add()
+	defer rs.running.done()
 	for _, s := range b.startNodes {
-		s.Start()
+		s.start(rs)
+	}
+	close(rs.ready)
+}
+
+// Stop cancels the context that is passed to the pipeline nodes. It does not
+// wait for the pipeline to be drained. You can use the Done method for that.
+// It can be safely invoked concurrently with Start or StartCtx. If the Runner
+// hasn't been started yet, its context is cancelled as soon as it starts.
+func (b *Runner) Stop() {
+	rs := b.state
+	rs.startMt.Lock()
+	defer rs.startMt.Unlock()
+	rs.stopped = true
+	if rs.cancel != nil {
+		rs.cancel()
 	}
 }
 
 // Done returns a channel that is closed when all the nodes of the
 // pipeline have stopped processing data. This is, the functions running
 // the node logic have returned.
+// Multiple invocations of Done return the same channel.
 func (b *Runner) Done() <-chan struct{} {
-	done := make(chan struct{})
-	go func() {
+	b.doneOnce.Do(func() {
+		b.done = make(chan struct{})
+		b.nodesMt.RLock()
+		finalNodes := make([]doneable, 0, len(b.finalNodes))
 		for _, s := range b.finalNodes {
-			<-s.Done()
+			finalNodes = append(finalNodes, s)
 		}
-		close(done)
+		b.nodesMt.RUnlock()
+		go func() {
+			for _, s := range finalNodes {
+				<-s.Done()
+			}
+			b.state.running.wait()
+			close(b.done)
+		}()
+	})
+	return b.done
+}
+
+// Err returns the errors that have been returned by the pipeline nodes until now, joined
+// into a single error. Each node error is wrapped into a *NodeError instance.
+// It returns nil if no node has returned any error.
+func (b *Runner) Err() error {
+	return b.state.err()
+}
+
+// Wait blocks until all the nodes of the pipeline have stopped processing data (see Done),
+// and returns the errors that have been returned by the pipeline nodes, if any (see Err).
+func (b *Runner) Wait() error {
+	<-b.Done()
+	return b.Err()
+}
+
+// runState holds the state that is shared by all the nodes of a running pipeline.
+type runState struct {
+	// startMt protects ctx, cancel and stopped, which are set by Runner.StartCtx and Runner.Stop.
+	// The nodes can read ctx and cancel without it, as they run after the Runner is started.
+	startMt       sync.Mutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+	stopped       bool
+	cancelOnError bool
+	// reconfigurable nodes send their data through a connect.Switch (see Reconfigurable)
+	reconfigurable bool
+
+	// running counts the node goroutines that haven't returned yet
+	running goroutines
+	// ready is closed when all the nodes have been started and connected, so the node
+	// goroutines can't close any channel while other senders aren't connected yet.
+	// Reconfigure replaces it while the new nodes are started and connected.
+	ready chan struct{}
+	// runningNodes counts the running instances of the function of each node, by node name
+	runningMt    sync.Mutex
+	runningNodes map[string]int
+
+	errsMt sync.Mutex
+	errs   []error
+}
+
+func newRunState(options *creationOptions) *runState {
+	return &runState{
+		cancelOnError:  options.cancelOnError,
+		reconfigurable: options.reconfigurable,
+		ready:          make(chan struct{}),
+		runningNodes:   map[string]int{},
+	}
+}
+
+// started returns whether the Runner has been started
+func (rs *runState) started() bool {
+	rs.startMt.Lock()
+	defer rs.startMt.Unlock()
+	return rs.ctx != nil
+}
+
+// run the node function in a goroutine that is accounted by the running counter
+func (rs *runState) run(fn func()) {
+	rs.running.add()
+	ready := rs.ready
+	go func() {
+		defer rs.running.done()
+		<-ready
+		fn()
 	}()
-	return done
+}
+
+// runNode invokes the function of the node, recovering from panics according to the
+// provided PanicPolicy and reporting any returned error.
+func (rs *runState) runNode(name string, policy PanicPolicy, fn func() error) {
+	rs.setRunning(name, 1)
+	defer rs.setRunning(name, -1)
+	for {
+		recovered, err := invoke(policy, fn)
+		if err != nil {
+			rs.nodeError(name, err)
+		}
+		if !recovered {
+			return
+		}
+		switch policy {
+		case PanicStopPipeline:
+			rs.cancel()
+			return
+		case PanicRestartNode:
+			// restarting start nodes would produce new data after the pipeline is stopped
+			if rs.ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// invoke the function, recovering and returning a *PanicError if the policy allows it
+func invoke(policy PanicPolicy, fn func() error) (recovered bool, err error) {
+	if policy != PanicCrash {
+		defer func() {
+			if r := recover(); r != nil {
+				recovered, err = true, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+	}
+	return false, fn()
+}
+
+// nodeError records the error returned by a node and, if configured, cancels
+// the rest of the pipeline.
+func (rs *runState) nodeError(node string, err error) {
+	rs.errsMt.Lock()
+	rs.errs = append(rs.errs, &NodeError{Node: node, Err: err})
+	rs.errsMt.Unlock()
+	if rs.cancelOnError {
+		rs.cancel()
+	}
+}
+
+func (rs *runState) setRunning(node string, delta int) {
+	rs.runningMt.Lock()
+	defer rs.runningMt.Unlock()
+	rs.runningNodes[node] += delta
+}
+
+func (rs *runState) isRunning(node string) bool {
+	rs.runningMt.Lock()
+	defer rs.runningMt.Unlock()
+	return rs.runningNodes[node] > 0
+}
+
+func (rs *runState) err() error {
+	rs.errsMt.Lock()
+	defer rs.errsMt.Unlock()
+	return errors.Join(rs.errs...)
+}
+
+// goroutines counts the running goroutines of a pipeline. Unlike sync.WaitGroup, it allows
+// safely checking whether the pipeline is still running before adding a goroutine.
+type goroutines struct {
+	mt    sync.Mutex
+	count int
+	// idle is closed when the count reaches zero
+	idle chan struct{}
+}
+
+func (g *goroutines) add() {
+	g.mt.Lock()
+	defer g.mt.Unlock()
+	if g.count == 0 {
+		g.idle = make(chan struct{})
+	}
+	g.count++
+}
+
+// addRunning adds a goroutine only if there are other running goroutines
+func (g *goroutines) addRunning() bool {
+	g.mt.Lock()
+	defer g.mt.Unlock()
+	if g.count == 0 {
+		return false
+	}
+	g.count++
+	return true
+}
+
+func (g *goroutines) done() {
+	g.mt.Lock()
+	defer g.mt.Unlock()
+	g.count--
+	if g.count == 0 {
+		close(g.idle)
+	}
+}
+
+// wait blocks until the count is zero
+func (g *goroutines) wait() {
+	for {
+		g.mt.Lock()
+		if g.count == 0 {
+			g.mt.Unlock()
+			return
+		}
+		idle := g.idle
+		g.mt.Unlock()
+		<-idle
+	}
 }
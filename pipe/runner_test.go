@@ -0,0 +1,187 @@
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+func infiniteCounter(ctx context.Context, out chan<- int) {
+	for i := 1; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case out <- i:
+		}
+	}
+}
+
+func TestRunner_Stop(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStartCtx(p, start, infiniteCounter)
+	pipe.AddMiddle(p, mid, EvenFilter)
+	received := make(chan int, 10)
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			select {
+			case received <- i:
+			default:
+			}
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	assert.Equal(t, 2, helpers.ReadChannel(t, received, timeout))
+	assert.Equal(t, 4, helpers.ReadChannel(t, received, timeout))
+
+	r.Stop()
+	helpers.ReadChannel(t, r.Done(), timeout)
+}
+
+func TestRunner_StopConcurrentlyWithStart(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		p := pipe.NewBuilder(&smfPipe{})
+		pipe.AddStartCtx(p, start, infiniteCounter)
+		pipe.AddMiddle(p, mid, EvenFilter)
+		pipe.AddFinal(p, final, func(in <-chan int) {
+			for range in {
+			}
+		})
+		r, err := p.Build()
+		require.NoError(t, err)
+
+		// the Stop invocation is never lost, despite it might happen before the Runner starts
+		stopped := make(chan struct{})
+		go func() {
+			r.Stop()
+			close(stopped)
+		}()
+		r.Start()
+		helpers.ReadChannel(t, stopped, timeout)
+		helpers.ReadChannel(t, r.Done(), timeout)
+		// starting the Runner again has no effect
+		r.Start()
+		require.NoError(t, r.Err())
+	}
+}
+
+func TestRunner_CancelContext(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStartProviderCtx(p, start, func() (pipe.StartFuncCtx[int], error) {
+		return infiniteCounter, nil
+	})
+	pipe.AddMiddle(p, mid, OddFilter)
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for range in {
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.StartCtx(ctx)
+	select {
+	case <-r.Done():
+		require.Fail(t, "pipeline shouldn't have finished before cancelling the context")
+	default: // ok!
+	}
+	cancel()
+	helpers.ReadChannel(t, r.Done(), timeout)
+}
+
+type ctxKey struct{}
+
+func TestRunner_ContextPropagation(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStartCtx(p, start, func(ctx context.Context, out chan<- int) {
+		out <- ctx.Value(ctxKey{}).(int)
+	})
+	pipe.AddMiddleCtx(p, mid, func(ctx context.Context, in <-chan int, out chan<- int) {
+		for i := range in {
+			out <- i + ctx.Value(ctxKey{}).(int)
+		}
+	})
+	var collected []int
+	pipe.AddFinalProviderCtx(p, final, func() (pipe.FinalFuncCtx[int], error) {
+		return func(ctx context.Context, in <-chan int) {
+			for i := range in {
+				collected = append(collected, i*ctx.Value(ctxKey{}).(int))
+			}
+		}, nil
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.StartCtx(context.WithValue(context.Background(), ctxKey{}, 3))
+	helpers.ReadChannel(t, r.Done(), timeout)
+
+	// (3 + 3) * 3
+	assert.Equal(t, []int{18}, collected)
+	// Done can be invoked multiple times
+	helpers.ReadChannel(t, r.Done(), timeout)
+}
+
+func TestRunner_NodeErrors(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStartErr(p, start, func(out chan<- int) error {
+		Counter(1, 10)(out)
+		return errors.New("start failed")
+	})
+	pipe.AddMiddleErr(p, mid, func(in <-chan int, out chan<- int) error {
+		out <- <-in
+		return MidError{}
+	})
+	var collected []int
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			collected = append(collected, i)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+	require.NoError(t, r.Err())
+
+	r.Start()
+	err = r.Wait()
+	require.Error(t, err)
+	assert.Equal(t, []int{1}, collected)
+
+	assert.ErrorIs(t, err, MidError{})
+	var nodeErr *pipe.NodeError
+	require.ErrorAs(t, err, &nodeErr)
+	assert.Contains(t, []string{"start", "mid"}, nodeErr.Node)
+	assert.Contains(t, err.Error(), "node start: start failed")
+	assert.Contains(t, err.Error(), "node mid: ")
+}
+
+func TestRunner_CancelOnError(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{}, pipe.CancelOnError())
+	pipe.AddStartCtx(p, start, infiniteCounter)
+	pipe.AddMiddle(p, mid, EvenFilter)
+	pipe.AddFinalErr(p, final, func(in <-chan int) error {
+		for i := range in {
+			if i > 10 {
+				return FinalError{}
+			}
+		}
+		return nil
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	err = r.Err()
+	var nodeErr *pipe.NodeError
+	require.ErrorAs(t, err, &nodeErr)
+	assert.Equal(t, "final", nodeErr.Node)
+	assert.ErrorIs(t, err, FinalError{})
+}
@@ -0,0 +1,97 @@
+package pipe
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// NodeStats is a snapshot of the runtime metrics of a node.
+type NodeStats struct {
+	// Kind of the node. Start nodes have no input, and Final nodes have no output, so their
+	// respective metrics are always zero.
+	Kind NodeKind
+	// StatsCollected is true if the node has been created with the CollectStats option. Otherwise,
+	// ItemsIn, ItemsOut, BlockedTime and AvgProcessingTime are not collected and are always zero.
+	StatsCollected bool
+	// ItemsIn is the number of items that have been received by the node.
+	// It is only collected when the node is created with the CollectStats option.
+	ItemsIn uint64
+	// ItemsOut is the number of items that have been sent by the node.
+	// It is only collected when the node is created with the CollectStats option.
+	ItemsOut uint64
+	// QueueLength is the number of items that are waiting in the input channel of the node,
+	// including the items that its senders are holding until the channel has room (see ChannelBufferLen).
+	QueueLength int
+	// QueueCapacity is the length of the buffer of the input channel of the node, plus the
+	// item that each of its senders can hold until the channel has room (see ChannelBufferLen).
+	QueueCapacity int
+	// Dropped is the number of items that have been discarded because the input channel of the
+	// node was full (see OnOverflow).
+	Dropped uint64
+	// BlockedTime is the accumulated time that the node has been blocked sending data, because
+	// the destination nodes were not ready to receive it (backpressure).
+	// It is only collected when the node is created with the CollectStats option.
+	BlockedTime time.Duration
+	// AvgProcessingTime is the average time that the node spends processing each input item.
+	// It is only collected when the node is created with the CollectStats option, and it
+	// is only measured for the items that the node receives while it is busy, or that are
+	// already queued when the node is ready to receive them.
+	AvgProcessingTime time.Duration
+	// Running is true while the function of the node is being executed.
+	Running bool
+}
+
+// Stats returns a snapshot of the runtime metrics of the nodes of the pipeline, by node name.
+// Bypassed and ignored nodes are not included.
+func (b *Runner) Stats() map[string]NodeStats {
+	stats := map[string]NodeStats{}
+	b.nodesMt.RLock()
+	defer b.nodesMt.RUnlock()
+	for _, n := range b.nodes {
+		if n.isNil() || n.kind() == bypassKind {
+			continue
+		}
+		ns := n.nodeStats()
+		ns.Kind = n.describe().Kind
+		ns.Running = b.state.isRunning(n.nodeName())
+		stats[n.nodeName()] = ns
+	}
+	return stats
+}
+
+func newStats(options *creationOptions) *connect.Stats {
+	if !options.collectStats {
+		return nil
+	}
+	return &connect.Stats{}
+}
+
+// queue provides type-agnostic access to the metrics of a connect.Joiner
+type queue interface {
+	Len() int
+	BufferLen() int
+	Dropped() uint64
+}
+
+// snapshot of the node stats. The stats argument is nil if the node does not collect stats.
+// The queue metrics of nodes with multiple inputs are aggregated.
+func snapshot(stats *connect.Stats, inputs ...queue) NodeStats {
+	ns := NodeStats{}
+	for _, in := range inputs {
+		ns.QueueLength += in.Len()
+		ns.QueueCapacity += in.BufferLen()
+		ns.Dropped += in.Dropped()
+	}
+	if stats != nil {
+		ns.StatsCollected = true
+		ns.ItemsIn = atomic.LoadUint64(&stats.ItemsIn)
+		ns.ItemsOut = atomic.LoadUint64(&stats.ItemsOut)
+		ns.BlockedTime = time.Duration(atomic.LoadInt64(&stats.BlockedNanos))
+		if samples := atomic.LoadUint64(&stats.ProcessingSamples); samples > 0 {
+			ns.AvgProcessingTime = time.Duration(atomic.LoadInt64(&stats.ProcessingNanos) / int64(samples))
+		}
+	}
+	return ns
+}
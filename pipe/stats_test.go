@@ -0,0 +1,76 @@
+package pipe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+)
+
+func TestStats(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{}, pipe.CollectStats())
+	pipe.AddStart(p, start, Counter(1, 10))
+	pipe.AddMiddle(p, mid, EvenFilter, pipe.ChannelBufferLen(3))
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for range in {
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	require.NoError(t, r.Wait())
+
+	stats := r.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, uint64(10), stats["start"].ItemsOut)
+	assert.Equal(t, uint64(10), stats["mid"].ItemsIn)
+	assert.Equal(t, uint64(5), stats["mid"].ItemsOut)
+	assert.Equal(t, 3, stats["mid"].QueueCapacity)
+	assert.Equal(t, uint64(5), stats["final"].ItemsIn)
+	assert.Zero(t, stats["final"].ItemsOut)
+
+	// the slow final node blocks the middle node
+	assert.NotZero(t, stats["mid"].BlockedTime)
+	assert.GreaterOrEqual(t, stats["final"].AvgProcessingTime, 5*time.Millisecond)
+}
+
+func TestStats_QueueLength(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{})
+	pipe.AddStart(p, start, Counter(1, 10))
+	pipe.AddMiddle(p, mid, EvenFilter, pipe.CollectStats())
+	unblock := make(chan struct{})
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		<-unblock
+		for range in {
+		}
+	}, pipe.ChannelBufferLen(2))
+	r, err := p.Build()
+	require.NoError(t, err)
+
+	r.Start()
+	// the final node is blocked, so its input channel gets full
+	assert.Eventually(t, func() bool {
+		return r.Stats()["final"].QueueLength == 2
+	}, timeout, time.Millisecond)
+	stats := r.Stats()
+	assert.Equal(t, 2, stats["final"].QueueCapacity)
+	// nodes without the CollectStats option don't count items
+	assert.Zero(t, stats["start"].ItemsOut)
+	assert.False(t, stats["start"].StatsCollected)
+	assert.NotZero(t, stats["mid"].ItemsIn)
+	assert.True(t, stats["mid"].StatsCollected)
+	assert.Equal(t, pipe.StartKind, stats["start"].Kind)
+	assert.Equal(t, pipe.FinalKind, stats["final"].Kind)
+	assert.True(t, stats["final"].Running)
+
+	close(unblock)
+	require.NoError(t, r.Wait())
+	stats = r.Stats()
+	assert.Equal(t, uint64(10), stats["mid"].ItemsIn)
+	assert.False(t, stats["final"].Running)
+}
@@ -0,0 +1,133 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe/internal/connect"
+)
+
+// tappable nodes send their data through a connect.Switch, to which taps can be attached
+type tappable[OUT any] interface {
+	outputSwitch() *connect.Switch[OUT]
+}
+
+func (sn *start[OUT]) outputSwitch() *connect.Switch[OUT] {
+	return sn.live().output
+}
+
+func (m *middle[IN, OUT]) outputSwitch() *connect.Switch[OUT] {
+	return m.live().output
+}
+
+// Tap is a temporary Final node that receives a copy of the data that is sent by a
+// node of a running pipeline (see AttachTap).
+type Tap struct {
+	detach  func()
+	dropped func() uint64
+	done    chan struct{}
+	// err is set before the done channel is closed
+	err error
+}
+
+// AttachTap attaches a temporary Final node (a Tap) to the output of the Start or Middle node with
+// the given name, in a running pipeline that has been built with the Reconfigurable option.
+// The tap function receives a copy of each item that the node sends after AttachTap returns.
+// If the input channel of the tap is full, the copies are discarded instead of blocking the
+// node, so the tap does not affect the rest of the pipeline. The ChannelBufferLen option specifies
+// the length of the input channel of the tap. If it is unbuffered (default), the tap only receives
+// the items that are sent while it is waiting for them.
+// The tap also accepts the OnOverflow(OverflowDropOldest) option, to discard the oldest items
+// instead of the newest ones, and the OnPanic option. Panics that are recovered by the
+// PanicStopPipeline policy only stop the tap.
+//
+// The input channel of the tap is closed when it is detached or when the node finishes. The taps
+// of the nodes that are kept by Runner.Reconfigure remain attached.
+// Taps are not part of the pipeline: they are not described by Graph nor Stats, and the Done channel
+// of the Runner does not wait for them to finish (see Tap.Done).
+func AttachTap[OUT any](r *Runner, node string, fn FinalFunc[OUT], opts ...Option) (*Tap, error) {
+	rs := r.state
+	switch {
+	case !rs.reconfigurable:
+		return nil, errors.New("the pipeline must be built with the Reconfigurable option")
+	case !rs.started():
+		return nil, errors.New("the pipeline has not been started")
+	}
+	var found pipeNode
+	r.nodesMt.RLock()
+	for _, n := range r.nodes {
+		if !n.isNil() && n.nodeName() == node {
+			found = n
+		}
+	}
+	r.nodesMt.RUnlock()
+	if found == nil {
+		return nil, fmt.Errorf("unknown node %q", node)
+	}
+	sender, ok := found.(tappable[OUT])
+	if !ok {
+		return nil, fmt.Errorf("node %s can't be tapped: it is not a Start or Middle node sending %s",
+			node, typeOf[OUT]())
+	}
+	output := sender.outputSwitch()
+	if output == nil {
+		return nil, fmt.Errorf("node %s can't be tapped: it has no outputs", node)
+	}
+
+	options := getOptions(opts...)
+	joiner := connect.NewJoiner[OUT](options.channelBufferLen)
+	if options.overflow == OverflowDropOldest {
+		joiner.SetOverflow(connect.DropOldest, nil)
+	} else {
+		joiner.SetOverflow(connect.DropNewest, nil)
+	}
+	if !output.AddTap(&joiner) {
+		return nil, fmt.Errorf("node %s can't be tapped: it has already finished", node)
+	}
+	tap := &Tap{
+		detach:  func() { output.RemoveTap(&joiner) },
+		dropped: joiner.Dropped,
+		done:    make(chan struct{}),
+	}
+	in := joiner.Receiver()
+	go func() {
+		for {
+			recovered, err := invoke(options.panicPolicy, func() error {
+				fn(in)
+				return nil
+			})
+			if err != nil {
+				tap.err = err
+			}
+			if !recovered || options.panicPolicy != PanicRestartNode {
+				break
+			}
+		}
+		close(tap.done)
+		drain(in)
+	}()
+	return tap, nil
+}
+
+// Detach the tap from its node, closing its input channel. Invoking Detach more than once,
+// or after the node has finished, has no effect.
+func (t *Tap) Detach() {
+	t.detach()
+}
+
+// Done returns a channel that is closed when the tap function has returned.
+func (t *Tap) Done() <-chan struct{} {
+	return t.done
+}
+
+// Dropped returns the number of items that have been discarded because the input channel
+// of the tap was full.
+func (t *Tap) Dropped() uint64 {
+	return t.dropped()
+}
+
+// Err returns the *PanicError of the last panic of the tap function, if its PanicPolicy
+// recovered it. It must be invoked after the Done channel is closed.
+func (t *Tap) Err() error {
+	return t.err
+}
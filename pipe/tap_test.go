@@ -0,0 +1,116 @@
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mariomac/pipes/pipe"
+	helpers "github.com/mariomac/pipes/testers"
+)
+
+func TestAttachTap(t *testing.T) {
+	p := pipe.NewBuilder(&smfPipe{}, pipe.Reconfigurable())
+	pipe.AddStartCtx(p, start, infiniteCounter)
+	pipe.AddMiddle(p, mid, EvenFilter)
+	received := make(chan int, 10)
+	pipe.AddFinal(p, final, func(in <-chan int) {
+		for i := range in {
+			select {
+			case received <- i:
+			default:
+			}
+		}
+	})
+	r, err := p.Build()
+	require.NoError(t, err)
+	r.Start()
+
+	tapped := make(chan int)
+	tap, err := pipe.AttachTap(r, "mid", func(in <-chan int) {
+		for i := range in {
+			tapped <- i
+		}
+	}, pipe.ChannelBufferLen(10))
+	require.NoError(t, err)
+
+	// the tap receives a copy of the items that are sent by the node
+	first := helpers.ReadChannel(t, tapped, timeout)
+	assert.Zero(t, first%2)
+	assert.Greater(t, helpers.ReadChannel(t, tapped, timeout), first)
+
+	// a tap that doesn't read its input doesn't block the pipeline
+	block := make(chan struct{})
+	blocked, err := pipe.AttachTap(r, "mid", func(in <-chan int) {
+		<-block
+	}, pipe.ChannelBufferLen(1))
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		helpers.ReadChannel(t, received, timeout)
+	}
+	assert.Greater(t, blocked.Dropped(), uint64(0))
+
+	// detaching the tap closes its input
+	tap.Detach()
+	tap.Detach()
+	go func() {
+		for range tapped {
+		}
+	}()
+	helpers.ReadChannel(t, tap.Done(), timeout)
+	helpers.ReadChannel(t, received, timeout)
+
+	// the runner doesn't wait for the taps to finish
+	r.Stop()
+	helpers.ReadChannel(t, r.Done(), timeout)
+	select {
+	case <-blocked.Done():
+		require.Fail(t, "blocked tap shouldn't have finished")
+	default: // ok!
+	}
+	close(block)
+	helpers.ReadChannel(t, blocked.Done(), timeout)
+	require.NoError(t, blocked.Err())
+	require.NoError(t, r.Err())
+}
+
+func TestAttachTap_Errors(t *testing.T) {
+	build := func(opts ...pipe.Option) *pipe.Runner {
+		p := pipe.NewBuilder(&smfPipe{}, opts...)
+		pipe.AddStartCtx(p, start, infiniteCounter)
+		pipe.AddMiddle(p, mid, EvenFilter)
+		pipe.AddFinal(p, final, func(in <-chan int) {
+			for range in {
+			}
+		})
+		r, err := p.Build()
+		require.NoError(t, err)
+		return r
+	}
+	printer := func(in <-chan int) {
+		for range in {
+		}
+	}
+
+	r := build()
+	r.Start()
+	_, err := pipe.AttachTap(r, "mid", printer)
+	assert.Error(t, err)
+	r.Stop()
+
+	r = build(pipe.Reconfigurable())
+	_, err = pipe.AttachTap(r, "mid", printer)
+	assert.Error(t, err)
+	r.Start()
+	defer r.Stop()
+	_, err = pipe.AttachTap(r, "undefined", printer)
+	assert.EqualError(t, err, `unknown node "undefined"`)
+	_, err = pipe.AttachTap(r, "final", printer)
+	assert.EqualError(t, err, "node final can't be tapped: it is not a Start or Middle node sending int")
+	_, err = pipe.AttachTap(r, "mid", func(in <-chan string) {})
+	assert.Error(t, err)
+	tap, err := pipe.AttachTap(r, "start", printer)
+	require.NoError(t, err)
+	tap.Detach()
+}
@@ -0,0 +1,242 @@
+package pipe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError is returned by the Builder when the nodes connected by the NodesMap
+// would form a pipeline that can't run properly. Each field lists the names of the
+// nodes that are affected by a given problem.
+type ValidationError struct {
+	// Dangling Start, Middle or bypassed nodes, which don't send data to any node.
+	Dangling []string
+	// Unreachable Middle and Final nodes, which don't receive data from any Start node
+	// and then would never finish.
+	Unreachable []string
+	// SelfLoops lists the nodes that send data to themselves.
+	SelfLoops []string
+	// Cycles lists the paths of the loops that are formed by two or more nodes. Each path
+	// starts and ends with the same node. Loops are only allowed if they are explicitly
+	// closed by means of the Sender.FeedbackTo method.
+	Cycles [][]string
+	// UndefinedDestinations lists the nodes that send data to a NodesMap field that
+	// hasn't been defined by any Add* function.
+	UndefinedDestinations []string
+	// UnconnectedOutputs lists the outputs of demux nodes that have been declared by DemuxAdd
+	// but are not connected to any node, as "node.output".
+	UnconnectedOutputs []string
+	// ConflictingOutputs lists the outputs of demux nodes that have been declared by DemuxAdd
+	// more than once, with different types, as "node.output".
+	ConflictingOutputs []string
+	// UnconnectedInputs lists the inputs of multi-input nodes (e.g. Middle2 or Final2)
+	// that are not connected to any sender, as "node.in1", "node.in2"...
+	UnconnectedInputs []string
+	// MismatchedFanOutKeys lists the sender nodes whose FanOutByKey function does not accept
+	// the type of their output (or the type of any of their outputs, for demux nodes).
+	MismatchedFanOutKeys []string
+	// MismatchedSpills lists the receiver nodes whose OnOverflowSpill function does not accept
+	// the type of their input (or any of their input types, for multi-input nodes).
+	MismatchedSpills []string
+}
+
+func (e *ValidationError) Error() string {
+	var problems []string
+	problems = appendProblem(problems, "dangling nodes", e.Dangling)
+	problems = appendProblem(problems, "unreachable nodes", e.Unreachable)
+	problems = appendProblem(problems, "nodes sending data to themselves", e.SelfLoops)
+	for _, cycle := range e.Cycles {
+		problems = append(problems, "cycle ["+strings.Join(cycle, " -> ")+"]")
+	}
+	problems = appendProblem(problems, "nodes sending data to undefined nodes", e.UndefinedDestinations)
+	problems = appendProblem(problems, "unconnected outputs", e.UnconnectedOutputs)
+	problems = appendProblem(problems, "outputs declared with different types", e.ConflictingOutputs)
+	problems = appendProblem(problems, "unconnected inputs", e.UnconnectedInputs)
+	problems = appendProblem(problems, "FanOutByKey functions not matching the output type", e.MismatchedFanOutKeys)
+	problems = appendProblem(problems, "OnOverflowSpill functions not matching the input type", e.MismatchedSpills)
+	return "invalid pipeline: " + strings.Join(problems, "; ")
+}
+
+func appendProblem(problems []string, problem string, nodes []string) []string {
+	if len(nodes) == 0 {
+		return problems
+	}
+	return append(problems, problem+" ["+strings.Join(nodes, ", ")+"]")
+}
+
+func (e *ValidationError) empty() bool {
+	return len(e.Dangling) == 0 && len(e.Unreachable) == 0 &&
+		len(e.SelfLoops) == 0 && len(e.UndefinedDestinations) == 0 && len(e.Cycles) == 0 &&
+		len(e.UnconnectedOutputs) == 0 && len(e.ConflictingOutputs) == 0 && len(e.UnconnectedInputs) == 0 &&
+		len(e.MismatchedFanOutKeys) == 0 && len(e.MismatchedSpills) == 0
+}
+
+// validate walks the graph of connected nodes and returns a *ValidationError
+// if the pipeline can't properly run.
+func validate(nodes []pipeNode) error {
+	verr := &ValidationError{}
+	reachable := map[pipeNode]struct{}{}
+	for _, n := range nodes {
+		if n.kind() == startKind && !n.isNil() {
+			markReachable(n, reachable)
+		}
+	}
+	for _, n := range nodes {
+		if n.isNil() {
+			continue
+		}
+		if n.kind() != finalKind && !hasOutputs(n, map[pipeNode]struct{}{}) {
+			verr.Dangling = append(verr.Dangling, n.nodeName())
+		}
+		if _, ok := reachable[n]; !ok && (n.kind() == middleKind || n.kind() == finalKind) {
+			verr.Unreachable = append(verr.Unreachable, n.nodeName())
+		}
+		for _, dst := range n.destinations() {
+			if dst == nil {
+				verr.UndefinedDestinations = append(verr.UndefinedDestinations, n.nodeName())
+				break
+			}
+		}
+		for _, dst := range n.destinations() {
+			if dst == n {
+				verr.SelfLoops = append(verr.SelfLoops, n.nodeName())
+				break
+			}
+		}
+		if dm, ok := n.(Demuxer); ok {
+			validateOutputs(n.nodeName(), dm.demuxOutputs(), verr)
+		}
+		if ks, ok := n.(keyedSender); ok && !ks.fanOutKeyMatches() {
+			verr.MismatchedFanOutKeys = append(verr.MismatchedFanOutKeys, n.nodeName())
+		}
+		if sr, ok := n.(spillReceiver); ok && !sr.spillMatches() {
+			verr.MismatchedSpills = append(verr.MismatchedSpills, n.nodeName())
+		}
+		if mi, ok := n.(multiInputNode); ok {
+			for i, in := range mi.inputPorts() {
+				if !in.isConnected() {
+					verr.UnconnectedInputs = append(verr.UnconnectedInputs, fmt.Sprintf("%s.in%d", n.nodeName(), i+1))
+				}
+			}
+		}
+	}
+	verr.Cycles = findCycles(nodes)
+	if verr.empty() {
+		return nil
+	}
+	sort.Strings(verr.Dangling)
+	sort.Strings(verr.Unreachable)
+	sort.Strings(verr.SelfLoops)
+	sort.Strings(verr.UndefinedDestinations)
+	sort.Strings(verr.UnconnectedOutputs)
+	sort.Strings(verr.ConflictingOutputs)
+	sort.Strings(verr.UnconnectedInputs)
+	sort.Strings(verr.MismatchedFanOutKeys)
+	sort.Strings(verr.MismatchedSpills)
+	return verr
+}
+
+func validateOutputs(node string, outs *demuxOutputs, verr *ValidationError) {
+	for _, name := range outs.names {
+		out := outs.byName[name]
+		if len(out.destinations()) == 0 && len(out.feedbackDestinations()) == 0 {
+			verr.UnconnectedOutputs = append(verr.UnconnectedOutputs, node+"."+name)
+		}
+	}
+	for _, name := range outs.conflicting {
+		verr.ConflictingOutputs = append(verr.ConflictingOutputs, node+"."+name)
+	}
+}
+
+func markReachable(n pipeNode, reachable map[pipeNode]struct{}) {
+	for _, dst := range append(n.destinations(), n.feedbackDestinations()...) {
+		if dst == nil || dst.isNil() {
+			continue
+		}
+		if _, ok := reachable[dst]; !ok {
+			reachable[dst] = struct{}{}
+			markReachable(dst, reachable)
+		}
+	}
+}
+
+// hasOutputs returns whether the node sends data to any non-nil node. Bypassed nodes
+// are not accounted as outputs unless they also have outputs.
+func hasOutputs(n pipeNode, visited map[pipeNode]struct{}) bool {
+	visited[n] = struct{}{}
+	for _, dst := range append(n.destinations(), n.feedbackDestinations()...) {
+		if dst == nil || dst.isNil() {
+			continue
+		}
+		if dst.kind() != bypassKind {
+			return true
+		}
+		if _, ok := visited[dst]; !ok && hasOutputs(dst, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// findCycles returns the path of the cycles that are formed by the SendTo connections
+// (ignoring the FeedbackTo connections and the self-loops).
+// Each path is rotated to start by the lowest node name, to provide a predictable output.
+func findCycles(nodes []pipeNode) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[pipeNode]int{}
+	var path []pipeNode
+	var cycles [][]string
+	var visit func(n pipeNode)
+	visit = func(n pipeNode) {
+		state[n] = visiting
+		path = append(path, n)
+		for _, dst := range n.destinations() {
+			if dst == nil || dst.isNil() || dst == n {
+				continue
+			}
+			switch state[dst] {
+			case unvisited:
+				visit(dst)
+			case visiting:
+				cycles = append(cycles, cyclePath(path, dst))
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = visited
+	}
+	for _, n := range nodes {
+		if !n.isNil() && state[n] == unvisited {
+			visit(n)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+	return cycles
+}
+
+// cyclePath returns the names of the nodes of the path from the loop start
+// to the end of the path, rotated to start by the lowest node name
+func cyclePath(path []pipeNode, loopStart pipeNode) []string {
+	start := len(path) - 1
+	for path[start] != loopStart {
+		start--
+	}
+	loop := path[start:]
+	first := 0
+	for i := range loop {
+		if loop[i].nodeName() < loop[first].nodeName() {
+			first = i
+		}
+	}
+	names := make([]string, 0, len(loop)+1)
+	for i := range loop {
+		names = append(names, loop[(first+i)%len(loop)].nodeName())
+	}
+	return append(names, names[0])
+}